@@ -0,0 +1,239 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// AppVersion is a deployed version of an app together with the deployment that
+// created it.
+type AppVersion struct {
+	App AppListResponse
+	// Status of the deployment that created the version.
+	Status DeploymentListResponseStatus
+	// Status reason of the deployment, if any.
+	StatusReason string
+	// Time the deployment was created.
+	DeployedAt time.Time
+	// The deployment's entrypoint, relative to the deployed code.
+	EntrypointRelPath string
+}
+
+// Versions lists the versions of an app, newest deployment first, with the
+// status of the deployment that created each of them.
+func (r *AppService) Versions(ctx context.Context, appName string, opts ...option.RequestOption) (res []AppVersion, err error) {
+	if appName == "" {
+		return nil, errors.New("missing required appName parameter")
+	}
+	deployments := map[string]DeploymentListResponse{}
+	depService := NewDeploymentService(r.Options...)
+	depIter := depService.ListAutoPaging(ctx, DeploymentListParams{AppName: String(appName)}, opts...)
+	for depIter.Next() {
+		dep := depIter.Current()
+		deployments[dep.ID] = dep
+	}
+	if err := depIter.Err(); err != nil {
+		return nil, err
+	}
+
+	iter := r.ListAutoPaging(ctx, AppListParams{AppName: String(appName)}, opts...)
+	for iter.Next() {
+		app := iter.Current()
+		v := AppVersion{App: app}
+		if dep, ok := deployments[app.Deployment]; ok {
+			v.Status = dep.Status
+			v.StatusReason = dep.StatusReason
+			v.DeployedAt = dep.CreatedAt
+			v.EntrypointRelPath = dep.EntrypointRelPath
+		} else if app.Deployment != "" {
+			dep, err := depService.Get(ctx, app.Deployment, opts...)
+			if err != nil {
+				return nil, err
+			}
+			v.Status = DeploymentListResponseStatus(dep.Status)
+			v.StatusReason = dep.StatusReason
+			v.DeployedAt = dep.CreatedAt
+			v.EntrypointRelPath = dep.EntrypointRelPath
+		}
+		res = append(res, v)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].DeployedAt.After(res[j].DeployedAt) })
+	return res, nil
+}
+
+// AppVersionDiff describes what changed between two app versions.
+type AppVersionDiff struct {
+	// Actions present only in the newer version.
+	AddedActions []string
+	// Actions present only in the older version.
+	RemovedActions []string
+	// Environment variables that differ, sorted by name.
+	EnvVars []EnvVarChange
+}
+
+// EnvVarChange is a single environment variable that differs between two sets.
+type EnvVarChange struct {
+	Name string
+	// Old value, empty if the variable was added.
+	From string
+	// New value, empty if the variable was removed.
+	To string
+	// Any of "added", "removed", "changed".
+	Kind string
+}
+
+// Empty reports whether the versions have the same actions and env vars.
+func (d AppVersionDiff) Empty() bool {
+	return len(d.AddedActions) == 0 && len(d.RemovedActions) == 0 && len(d.EnvVars) == 0
+}
+
+// DiffAppVersions compares the actions and env vars of two app versions.
+func DiffAppVersions(from, to AppListResponse) AppVersionDiff {
+	var diff AppVersionDiff
+	fromActions := map[string]bool{}
+	for _, a := range from.Actions {
+		fromActions[a.Name] = true
+	}
+	toActions := map[string]bool{}
+	for _, a := range to.Actions {
+		toActions[a.Name] = true
+		if !fromActions[a.Name] {
+			diff.AddedActions = append(diff.AddedActions, a.Name)
+		}
+	}
+	for _, a := range from.Actions {
+		if !toActions[a.Name] {
+			diff.RemovedActions = append(diff.RemovedActions, a.Name)
+		}
+	}
+	sort.Strings(diff.AddedActions)
+	sort.Strings(diff.RemovedActions)
+	diff.EnvVars = diffEnvVars(from.EnvVars, to.EnvVars)
+	return diff
+}
+
+func diffEnvVars(from, to map[string]string) []EnvVarChange {
+	var changes []EnvVarChange
+	for k, v := range to {
+		old, ok := from[k]
+		switch {
+		case !ok:
+			changes = append(changes, EnvVarChange{Name: k, To: v, Kind: "added"})
+		case old != v:
+			changes = append(changes, EnvVarChange{Name: k, From: old, To: v, Kind: "changed"})
+		}
+	}
+	for k, v := range from {
+		if _, ok := to[k]; !ok {
+			changes = append(changes, EnvVarChange{Name: k, From: v, Kind: "removed"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+type AppRollbackParams struct {
+	// Version to roll back to. Defaults to the newest version, other than the
+	// current one, whose deployment did not fail.
+	Version string
+	// Where the code of that version lives. Deployment records do not keep the
+	// uploaded code or its source, so exactly one of Source (usually pinned to a tag
+	// or commit) and Dir must be set.
+	Source DeploymentNewParamsSource
+	// Local directory holding the code of the version, see
+	// [DeploymentService.NewFromDir].
+	Dir string
+}
+
+type AppRollbackResponse struct {
+	// The version that was current before the rollback.
+	From AppVersion
+	// The version rolled back to.
+	To AppVersion
+	// The redeployment of the target version.
+	Deployment *DeploymentDeployResponse
+}
+
+// Rollback redeploys a previous version of an app with the entrypoint and env
+// vars it was deployed with, overwriting the version with Force, and waits until
+// the deployment is running.
+func (r *AppService) Rollback(ctx context.Context, appName string, params AppRollbackParams, opts ...option.RequestOption) (res *AppRollbackResponse, err error) {
+	if (params.Source.URL == "") == (params.Dir == "") {
+		return nil, errors.New("kernel: exactly one of Source and Dir must be set")
+	}
+	versions, err := r.Versions(ctx, appName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("kernel: app %s has no versions", appName)
+	}
+	res = &AppRollbackResponse{From: versions[0]}
+	found := false
+	for _, v := range versions[1:] {
+		if params.Version != "" {
+			found = v.App.Version == params.Version
+		} else {
+			found = v.Status != DeploymentListResponseStatusFailed && v.App.Version != res.From.App.Version
+		}
+		if found {
+			res.To = v
+			break
+		}
+	}
+	if !found {
+		if params.Version != "" {
+			return nil, fmt.Errorf("kernel: app %s has no earlier version %s", appName, params.Version)
+		}
+		return nil, fmt.Errorf("kernel: app %s has no earlier version to roll back to", appName)
+	}
+
+	body := DeploymentNewParams{
+		Version: String(res.To.App.Version),
+		Force:   Bool(true),
+	}
+	envVars := map[string]string{}
+	for k, v := range res.To.App.EnvVars {
+		if k != DeploymentContentHashEnvVar {
+			envVars[k] = v
+		}
+	}
+	if len(envVars) > 0 {
+		body.EnvVars = envVars
+	}
+
+	deployments := NewDeploymentService(r.Options...)
+	if params.Source.URL != "" {
+		body.Source = params.Source
+		if body.Source.Entrypoint == "" {
+			body.Source.Entrypoint = res.To.EntrypointRelPath
+		}
+		if body.Source.Type == "" {
+			body.Source.Type = "github"
+		}
+		res.Deployment, err = deployments.Deploy(ctx, body, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	body.EntrypointRelPath = String(res.To.EntrypointRelPath)
+	created, err := deployments.NewFromDir(ctx, params.Dir, DeploymentNewFromDirParams{Deployment: body}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res.Deployment, err = deployments.Wait(ctx, created.Deployment.ID, DeploymentWaitParams{AppName: String(res.To.App.AppName), Version: body.Version}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
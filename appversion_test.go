@@ -0,0 +1,85 @@
+package kernel_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk"
+)
+
+func TestAppVersionsAndRollback(t *testing.T) {
+	var deployed map[string]string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/deployments":
+			w.Header().Set("X-Next-Offset", "0")
+			fmt.Fprint(w, `[
+				{"id":"dep_1","created_at":"2025-01-01T00:00:00Z","region":"aws.us-east-1a","status":"stopped","entrypoint_rel_path":"index.ts"},
+				{"id":"dep_3","created_at":"2025-01-03T00:00:00Z","region":"aws.us-east-1a","status":"running","entrypoint_rel_path":"main.ts"}
+			]`)
+		case r.Method == http.MethodGet && r.URL.Path == "/deployments/dep_2":
+			fmt.Fprint(w, `{"id":"dep_2","created_at":"2025-01-02T00:00:00Z","region":"aws.us-east-1a","status":"failed","status_reason":"crashed"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/apps":
+			w.Header().Set("X-Next-Offset", "0")
+			fmt.Fprint(w, `[
+				{"id":"a1","app_name":"my-app","version":"1","deployment":"dep_1","region":"aws.us-east-1a","actions":[{"name":"scrape"}],"env_vars":{"MODE":"prod","OLD":"x"}},
+				{"id":"a2","app_name":"my-app","version":"2","deployment":"dep_2","region":"aws.us-east-1a","actions":[],"env_vars":{}},
+				{"id":"a3","app_name":"my-app","version":"3","deployment":"dep_3","region":"aws.us-east-1a","actions":[{"name":"scrape"},{"name":"login"}],"env_vars":{"MODE":"dev"}}
+			]`)
+		case r.Method == http.MethodPost && r.URL.Path == "/deployments":
+			r.ParseMultipartForm(1 << 20)
+			deployed = map[string]string{}
+			for k, v := range r.MultipartForm.Value {
+				deployed[k] = v[0]
+			}
+			fmt.Fprint(w, `{"id":"dep_4","created_at":"2025-01-04T00:00:00Z","region":"aws.us-east-1a","status":"queued"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/deployments/dep_4/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"event\":\"app_version_summary\",\"timestamp\":\"2025-01-04T00:00:00Z\",\"id\":\"a4\",\"app_name\":\"my-app\",\"version\":\"1\",\"region\":\"aws.us-east-1a\",\"actions\":[]}\n\n")
+			fmt.Fprint(w, "data: {\"event\":\"deployment_state\",\"timestamp\":\"2025-01-04T00:00:00Z\",\"deployment\":{\"id\":\"dep_4\",\"created_at\":\"2025-01-04T00:00:00Z\",\"region\":\"aws.us-east-1a\",\"status\":\"running\"}}\n\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	versions, err := client.Apps.Versions(context.Background(), "my-app")
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	var got []string
+	for _, v := range versions {
+		got = append(got, v.App.Version+":"+string(v.Status))
+	}
+	if want := []string{"3:running", "2:failed", "1:stopped"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("versions %v, want %v", got, want)
+	}
+
+	diff := kernel.DiffAppVersions(versions[2].App, versions[0].App)
+	if !reflect.DeepEqual(diff.AddedActions, []string{"login"}) || len(diff.RemovedActions) != 0 {
+		t.Errorf("unexpected action diff: %+v", diff)
+	}
+	wantEnv := []kernel.EnvVarChange{
+		{Name: "MODE", From: "prod", To: "dev", Kind: "changed"},
+		{Name: "OLD", From: "x", Kind: "removed"},
+	}
+	if !reflect.DeepEqual(diff.EnvVars, wantEnv) {
+		t.Errorf("env diff %+v, want %+v", diff.EnvVars, wantEnv)
+	}
+
+	res, err := client.Apps.Rollback(context.Background(), "my-app", kernel.AppRollbackParams{
+		Source: kernel.DeploymentNewParamsSource{URL: "https://github.com/acme/apps", Ref: "v1"},
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.From.App.Version != "3" || res.To.App.Version != "1" || res.Deployment.Deployment.Status != "running" {
+		t.Errorf("unexpected rollback: %+v", res)
+	}
+	if deployed["version"] != "1" || deployed["force"] != "true" || deployed["source.entrypoint"] != "index.ts" || deployed["env_vars.MODE"] != "prod" {
+		t.Errorf("unexpected redeploy body: %v", deployed)
+	}
+}
@@ -0,0 +1,326 @@
+package kernel
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// BrowserCaptureBundleParams selects what [BrowserService.CaptureBundle]
+// collects.
+type BrowserCaptureBundleParams struct {
+	// Where the zip archive is written. Required.
+	Output io.Writer
+	// Skip the screenshot of the screen.
+	SkipScreenshot bool
+	// Skip the session's replay recordings.
+	SkipReplays bool
+	// Stop replays that are still recording so they can be included. Otherwise
+	// they are listed in the index without a recording.
+	StopActiveReplays bool
+	// Supervisor processes whose recent logs are included, such as "chromium".
+	SupervisorProcesses []string
+	// Absolute paths of log files whose recent lines are included.
+	LogPaths []string
+	// Absolute paths of directories included as nested zip archives.
+	Directories []string
+	// Maximum number of downloads in flight at once. Defaults to 4.
+	Concurrency int
+}
+
+// Kind of an entry of a browser debug bundle.
+type BrowserBundleEntryKind string
+
+const (
+	BrowserBundleEntryKindMetadata   BrowserBundleEntryKind = "metadata"
+	BrowserBundleEntryKindScreenshot BrowserBundleEntryKind = "screenshot"
+	BrowserBundleEntryKindReplay     BrowserBundleEntryKind = "replay"
+	BrowserBundleEntryKindLog        BrowserBundleEntryKind = "log"
+	BrowserBundleEntryKindDirectory  BrowserBundleEntryKind = "directory"
+)
+
+// BrowserBundleEntry describes an item of a browser debug bundle.
+type BrowserBundleEntry struct {
+	// Path of the file in the archive. Empty if the item could not be collected.
+	Name string                 `json:"name,omitempty"`
+	Kind BrowserBundleEntryKind `json:"kind"`
+	// What was collected: the replay ID, supervisor process, log path or
+	// directory.
+	Source string `json:"source,omitempty"`
+	// Replay view URL, for replays.
+	URL  string `json:"url,omitempty"`
+	Size int64  `json:"size"`
+	// Why the item could not be collected.
+	Error string `json:"error,omitempty"`
+}
+
+// BrowserBundleIndex is written to index.json in a browser debug bundle and
+// returned by [BrowserService.CaptureBundle].
+type BrowserBundleIndex struct {
+	SessionID  string               `json:"session_id"`
+	CapturedAt time.Time            `json:"captured_at"`
+	Entries    []BrowserBundleEntry `json:"entries"`
+}
+
+// CaptureBundle collects debugging evidence for a browser session and writes it
+// to params.Output as a zip archive: the session metadata from Get, a screenshot,
+// the replay recordings, recent logs of the selected supervisor processes and
+// files, and the selected directories. Items are downloaded concurrently. An
+// item that cannot be collected does not fail the bundle; it is recorded in
+// index.json, the archive's last file, which is also returned. An error is only
+// returned if the archive cannot be written or ctx is done.
+func (r *BrowserService) CaptureBundle(ctx context.Context, id string, params BrowserCaptureBundleParams, opts ...option.RequestOption) (res *BrowserBundleIndex, err error) {
+	if id == "" {
+		return nil, errors.New("missing required id parameter")
+	}
+	if params.Output == nil {
+		return nil, errors.New("missing required Output parameter")
+	}
+	if params.Concurrency <= 0 {
+		params.Concurrency = 4
+	}
+
+	tmp, err := os.MkdirTemp("", "kernel-bundle-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	items := r.bundleItems(ctx, id, params, opts)
+	sem := make(chan struct{}, params.Concurrency)
+	var wg sync.WaitGroup
+	for i := range items {
+		item := &items[i]
+		if item.fetch == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				item.err = ctx.Err()
+				return
+			}
+			item.file = filepath.Join(tmp, fmt.Sprintf("%d", i))
+			item.err = writeBundleFile(item.file, func(w io.Writer) error { return item.fetch(ctx, w) })
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	res = &BrowserBundleIndex{SessionID: id, CapturedAt: time.Now().UTC()}
+	zw := zip.NewWriter(params.Output)
+	for _, item := range items {
+		entry := item.entry
+		if item.err != nil {
+			entry.Error = item.err.Error()
+		} else if item.fetch != nil {
+			size, err := addBundleFile(zw, entry.Name, item.file)
+			if err != nil {
+				return nil, err
+			}
+			entry.Size = size
+		}
+		if entry.Error != "" {
+			entry.Name = ""
+		}
+		res.Entries = append(res.Entries, entry)
+	}
+	w, err := zw.Create("index.json")
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(res); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+type bundleItem struct {
+	entry BrowserBundleEntry
+	fetch func(ctx context.Context, w io.Writer) error
+	file  string
+	err   error
+}
+
+// bundleItems lists what goes into a bundle. Replays are listed, and stopped if
+// requested, up front so that each recording becomes its own item.
+func (r *BrowserService) bundleItems(ctx context.Context, id string, params BrowserCaptureBundleParams, opts []option.RequestOption) []bundleItem {
+	items := []bundleItem{{
+		entry: BrowserBundleEntry{Name: "metadata.json", Kind: BrowserBundleEntryKindMetadata, Source: id},
+		fetch: func(ctx context.Context, w io.Writer) error {
+			browser, err := r.Get(ctx, id, BrowserGetParams{}, opts...)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, browser.RawJSON())
+			return err
+		},
+	}}
+
+	if !params.SkipScreenshot {
+		items = append(items, bundleItem{
+			entry: BrowserBundleEntry{Name: "screenshot.png", Kind: BrowserBundleEntryKindScreenshot},
+			fetch: func(ctx context.Context, w io.Writer) error {
+				return copyResponse(w)(r.Computer.CaptureScreenshot(ctx, id, BrowserComputerCaptureScreenshotParams{}, opts...))
+			},
+		})
+	}
+
+	if !params.SkipReplays {
+		replays, err := r.Replays.List(ctx, id, opts...)
+		if err != nil {
+			items = append(items, bundleItem{entry: BrowserBundleEntry{Kind: BrowserBundleEntryKindReplay, Error: err.Error()}})
+		} else {
+			for _, replay := range *replays {
+				item := bundleItem{entry: BrowserBundleEntry{
+					Name:   "replays/" + replay.ReplayID + ".mp4",
+					Kind:   BrowserBundleEntryKindReplay,
+					Source: replay.ReplayID,
+					URL:    replay.ReplayViewURL,
+				}}
+				if replay.FinishedAt.IsZero() {
+					if !params.StopActiveReplays {
+						item.entry.Error = "replay is still recording"
+						items = append(items, item)
+						continue
+					}
+					if err := r.Replays.Stop(ctx, replay.ReplayID, BrowserReplayStopParams{ID: id}, opts...); err != nil {
+						item.entry.Error = fmt.Sprintf("stopping replay: %s", err)
+						items = append(items, item)
+						continue
+					}
+				}
+				replayID := replay.ReplayID
+				item.fetch = func(ctx context.Context, w io.Writer) error {
+					return copyResponse(w)(r.Replays.Download(ctx, replayID, BrowserReplayDownloadParams{ID: id}, opts...))
+				}
+				items = append(items, item)
+			}
+		}
+	}
+
+	for _, process := range params.SupervisorProcesses {
+		items = append(items, bundleItem{
+			entry: BrowserBundleEntry{Name: "logs/supervisor-" + bundlePath(process) + ".log", Kind: BrowserBundleEntryKindLog, Source: process},
+			fetch: r.bundleLog(id, BrowserLogStreamParams{Source: BrowserLogStreamParamsSourceSupervisor, SupervisorProcess: String(process)}, opts),
+		})
+	}
+	for _, logPath := range params.LogPaths {
+		items = append(items, bundleItem{
+			entry: BrowserBundleEntry{Name: "logs/" + bundlePath(logPath), Kind: BrowserBundleEntryKindLog, Source: logPath},
+			fetch: r.bundleLog(id, BrowserLogStreamParams{Source: BrowserLogStreamParamsSourcePath, Path: String(logPath)}, opts),
+		})
+	}
+
+	for _, dir := range params.Directories {
+		items = append(items, bundleItem{
+			entry: BrowserBundleEntry{Name: "directories/" + bundlePath(dir) + ".zip", Kind: BrowserBundleEntryKindDirectory, Source: dir},
+			fetch: func(ctx context.Context, w io.Writer) error {
+				return copyResponse(w)(r.Fs.DownloadDirZip(ctx, id, BrowserFDownloadDirZipParams{Path: dir}, opts...))
+			},
+		})
+	}
+
+	// Paths listed twice, or spelled differently, would otherwise share an entry.
+	seen := map[string]bool{}
+	for i := range items {
+		name := items[i].entry.Name
+		if name == "" {
+			continue
+		}
+		ext := path.Ext(name)
+		for n := 2; seen[name]; n++ {
+			name = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(items[i].entry.Name, ext), n, ext)
+		}
+		seen[name] = true
+		items[i].entry.Name = name
+	}
+	return items
+}
+
+// bundleLog writes the recent lines of a log, one per line, prefixed with their
+// timestamp.
+func (r *BrowserService) bundleLog(id string, query BrowserLogStreamParams, opts []option.RequestOption) func(ctx context.Context, w io.Writer) error {
+	query.Follow = Bool(false)
+	return func(ctx context.Context, w io.Writer) error {
+		stream := r.Logs.StreamStreaming(ctx, id, query, opts...)
+		defer stream.Close()
+		for stream.Next() {
+			event := stream.Current()
+			if _, err := fmt.Fprintf(w, "%s %s\n", event.Timestamp.Format(time.RFC3339Nano), strings.TrimRight(event.Message, "\n")); err != nil {
+				return err
+			}
+		}
+		return stream.Err()
+	}
+}
+
+// copyResponse returns a function that copies the body of a download to w.
+func copyResponse(w io.Writer) func(res *http.Response, err error) error {
+	return func(res *http.Response, err error) error {
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		_, err = io.Copy(w, res.Body)
+		return err
+	}
+}
+
+// bundlePath turns an absolute path or name into a relative path within the
+// archive, keeping its directories.
+func bundlePath(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+	if name == "" {
+		return "root"
+	}
+	return name
+}
+
+func writeBundleFile(name string, fn func(w io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func addBundleFile(zw *zip.Writer, name string, file string) (int64, error) {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(w, f)
+}
@@ -0,0 +1,169 @@
+package kernel_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+func TestBrowserCaptureBundle(t *testing.T) {
+	var mu sync.Mutex
+	var stopped []string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/browsers/sess_1":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"session_id":"sess_1","cdp_ws_url":"wss://cdp","created_at":"2025-01-01T00:00:00Z","headless":false,"stealth":false,"timeout_seconds":60}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/browsers/sess_1/computer/screenshot":
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, "\x89PNG")
+		case r.Method == http.MethodGet && r.URL.Path == "/browsers/sess_1/replays":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `[{"replay_id":"rep_done","started_at":"2025-01-01T00:00:00Z","finished_at":"2025-01-01T00:01:00Z","replay_view_url":"https://replay/1"},{"replay_id":"rep_live","started_at":"2025-01-01T00:02:00Z","finished_at":null}]`)
+		case r.Method == http.MethodPost && r.URL.Path == "/browsers/sess_1/replays/rep_live/stop":
+			mu.Lock()
+			stopped = append(stopped, "rep_live")
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/browsers/sess_1/replays/"):
+			w.Header().Set("Content-Type", "video/mp4")
+			fmt.Fprint(w, "video "+strings.TrimPrefix(r.URL.Path, "/browsers/sess_1/replays/"))
+		case r.Method == http.MethodGet && r.URL.Path == "/browsers/sess_1/logs/stream":
+			q := r.URL.Query()
+			if q.Get("follow") != "false" || q.Get("source") != "supervisor" || q.Get("supervisor_process") != "chromium" {
+				t.Errorf("unexpected log query %q", r.URL.RawQuery)
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"event\":\"log\",\"message\":\"started\",\"timestamp\":\"2025-01-01T00:00:00Z\"}\n\n")
+			fmt.Fprint(w, "data: {\"event\":\"log\",\"message\":\"crashed\",\"timestamp\":\"2025-01-01T00:00:01Z\"}\n\n")
+		case r.Method == http.MethodGet && r.URL.Path == "/browsers/sess_1/fs/download_dir_zip":
+			if r.URL.Query().Get("path") == "/missing" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"code":"not_found","message":"no such directory"}`)
+				return
+			}
+			w.Header().Set("Content-Type", "application/zip")
+			fmt.Fprint(w, "zip of "+r.URL.Query().Get("path"))
+		default:
+			http.NotFound(w, r)
+		}
+	}), option.WithMaxRetries(0))
+
+	var buf bytes.Buffer
+	index, err := client.Browsers.CaptureBundle(context.Background(), "sess_1", kernel.BrowserCaptureBundleParams{
+		Output:              &buf,
+		StopActiveReplays:   true,
+		SupervisorProcesses: []string{"chromium"},
+		Directories:         []string{"/tmp/downloads", "/missing"},
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if fmt.Sprint(stopped) != "[rep_live]" {
+		t.Errorf("expected the active replay to be stopped, got %v", stopped)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+	want := map[string]string{
+		"screenshot.png":                "\x89PNG",
+		"replays/rep_done.mp4":          "video rep_done",
+		"replays/rep_live.mp4":          "video rep_live",
+		"logs/supervisor-chromium.log":  "2025-01-01T00:00:00Z started\n2025-01-01T00:00:01Z crashed\n",
+		"directories/tmp/downloads.zip": "zip of /tmp/downloads",
+	}
+	for name, content := range want {
+		if files[name] != content {
+			t.Errorf("%s = %q, want %q", name, files[name], content)
+		}
+	}
+	if !strings.Contains(files["metadata.json"], `"session_id":"sess_1"`) {
+		t.Errorf("unexpected metadata %q", files["metadata.json"])
+	}
+
+	var written kernel.BrowserBundleIndex
+	if err := json.Unmarshal([]byte(files["index.json"]), &written); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if len(written.Entries) != len(index.Entries) || written.SessionID != "sess_1" {
+		t.Errorf("expected the written index to match the returned one, got %+v", written)
+	}
+	var failed []string
+	for _, e := range index.Entries {
+		if e.Error != "" {
+			failed = append(failed, e.Source)
+		}
+	}
+	sort.Strings(failed)
+	if fmt.Sprint(failed) != "[/missing]" || len(index.Entries) != 7 {
+		t.Errorf("expected only the missing directory to fail, got %v of %d entries", failed, len(index.Entries))
+	}
+}
+
+func TestBrowserCaptureBundleDistinctNames(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/browsers/sess_1":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"session_id":"sess_1","cdp_ws_url":"wss://cdp","created_at":"2025-01-01T00:00:00Z","headless":false,"stealth":false,"timeout_seconds":60}`)
+		case "/browsers/sess_1/logs/stream":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: {\"event\":\"log\",\"message\":%q,\"timestamp\":\"2025-01-01T00:00:00Z\"}\n\n", r.URL.Query().Get("path"))
+		default:
+			http.NotFound(w, r)
+		}
+	}), option.WithMaxRetries(0))
+
+	var buf bytes.Buffer
+	_, err := client.Browsers.CaptureBundle(context.Background(), "sess_1", kernel.BrowserCaptureBundleParams{
+		Output:         &buf,
+		SkipScreenshot: true,
+		SkipReplays:    true,
+		LogPaths:       []string{"/a_b.log", "/a/b.log", "/a//b.log"},
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+	want := map[string]string{
+		"logs/a_b.log":   "2025-01-01T00:00:00Z /a_b.log\n",
+		"logs/a/b.log":   "2025-01-01T00:00:00Z /a/b.log\n",
+		"logs/a/b-2.log": "2025-01-01T00:00:00Z /a//b.log\n",
+	}
+	for name, content := range want {
+		if files[name] != content {
+			t.Errorf("%s = %q, want %q", name, files[name], content)
+		}
+	}
+}
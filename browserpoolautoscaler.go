@@ -0,0 +1,253 @@
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// Clock is a source of time. It lets tests drive time-based helpers such as
+// [BrowserPoolAutoscaler] without sleeping.
+type Clock interface {
+	Now() time.Time
+	// After returns a channel that receives the time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// BrowserPoolAutoscalerPolicy decides how a pool is resized.
+type BrowserPoolAutoscalerPolicy struct {
+	// Fraction of the pool that should be acquired, between 0 and 1. Defaults to
+	// 0.7.
+	TargetUtilization float64
+	// How far utilization may drift from the target before the pool is resized.
+	// Defaults to 0.1.
+	Tolerance float64
+	// Smallest size the pool is scaled to. Defaults to 1.
+	MinSize int64
+	// Largest size the pool is scaled to. Zero means no limit other than OrgLimit.
+	MaxSize int64
+	// Maximum number of browsers added or removed by one resize. Zero means no
+	// limit.
+	MaxStepUp   int64
+	MaxStepDown int64
+	// Minimum time after a resize before the pool is grown or shrunk again.
+	// They default to 1 minute and 5 minutes.
+	ScaleUpCooldown   time.Duration
+	ScaleDownCooldown time.Duration
+	// The organization's pooled sessions limit, which the sizes of all pools
+	// together cannot exceed. If set, the pool is not grown beyond what the other
+	// pools leave free. Zero means unknown.
+	OrgLimit int64
+}
+
+// BrowserPoolAutoscalerParams configures [BrowserPoolService.NewAutoscaler].
+type BrowserPoolAutoscalerParams struct {
+	Policy BrowserPoolAutoscalerPolicy
+	// Time between samples when running. Defaults to 30 seconds.
+	Interval time.Duration
+	// Defaults to the system clock.
+	Clock Clock
+	// Called with every decision, including the ones that leave the pool as it is.
+	OnDecision func(BrowserPoolScaleDecision)
+}
+
+// What an autoscaler decided to do with a pool.
+type BrowserPoolScaleAction string
+
+const (
+	BrowserPoolScaleActionNone BrowserPoolScaleAction = "none"
+	BrowserPoolScaleActionUp   BrowserPoolScaleAction = "scale_up"
+	BrowserPoolScaleActionDown BrowserPoolScaleAction = "scale_down"
+)
+
+// BrowserPoolScaleDecision records one sample of an autoscaler.
+type BrowserPoolScaleDecision struct {
+	Time   time.Time
+	Pool   string
+	Action BrowserPoolScaleAction
+	// Why the action was chosen, such as "cooldown" or "org limit".
+	Reason         string
+	AcquiredCount  int64
+	AvailableCount int64
+	Utilization    float64
+	// Size of the pool when sampled.
+	Size int64
+	// Size the policy asks for before cooldowns, step limits and the org limit.
+	DesiredSize int64
+	// Size the pool was resized to, or Size if it was not.
+	NewSize int64
+	// Error sampling or resizing the pool, if any.
+	Err error
+}
+
+// BrowserPoolAutoscaler resizes a pool to keep its utilization near a target.
+type BrowserPoolAutoscaler struct {
+	pools      *BrowserPoolService
+	pool       string
+	params     BrowserPoolAutoscalerParams
+	opts       []option.RequestOption
+	lastResize time.Time
+}
+
+// NewAutoscaler returns an autoscaler for the pool. Call Run to start it, or Step
+// to take a single sample.
+func (r *BrowserPoolService) NewAutoscaler(idOrName string, params BrowserPoolAutoscalerParams, opts ...option.RequestOption) *BrowserPoolAutoscaler {
+	p := &params.Policy
+	if p.TargetUtilization <= 0 || p.TargetUtilization > 1 {
+		p.TargetUtilization = 0.7
+	}
+	if p.Tolerance <= 0 {
+		p.Tolerance = 0.1
+	}
+	if p.MinSize <= 0 {
+		p.MinSize = 1
+	}
+	if p.ScaleUpCooldown <= 0 {
+		p.ScaleUpCooldown = time.Minute
+	}
+	if p.ScaleDownCooldown <= 0 {
+		p.ScaleDownCooldown = 5 * time.Minute
+	}
+	if params.Interval <= 0 {
+		params.Interval = 30 * time.Second
+	}
+	if params.Clock == nil {
+		params.Clock = systemClock{}
+	}
+	return &BrowserPoolAutoscaler{pools: r, pool: idOrName, params: params, opts: opts}
+}
+
+// Run samples the pool every interval until ctx is done, and returns ctx's
+// error. Errors sampling or resizing the pool are reported through OnDecision
+// and do not stop the loop.
+func (a *BrowserPoolAutoscaler) Run(ctx context.Context) error {
+	for {
+		a.Step(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-a.params.Clock.After(a.params.Interval):
+		}
+	}
+}
+
+// Step samples the pool once and resizes it if the policy says so.
+func (a *BrowserPoolAutoscaler) Step(ctx context.Context) (BrowserPoolScaleDecision, error) {
+	d := a.step(ctx)
+	if a.params.OnDecision != nil {
+		a.params.OnDecision(d)
+	}
+	return d, d.Err
+}
+
+func (a *BrowserPoolAutoscaler) step(ctx context.Context) (d BrowserPoolScaleDecision) {
+	p := a.params.Policy
+	d = BrowserPoolScaleDecision{Time: a.params.Clock.Now(), Pool: a.pool, Action: BrowserPoolScaleActionNone}
+	pool, err := a.pools.Get(ctx, a.pool, a.opts...)
+	if err != nil {
+		d.Reason, d.Err = "sampling failed", err
+		return d
+	}
+	d.AcquiredCount, d.AvailableCount = pool.AcquiredCount, pool.AvailableCount
+	d.Size = pool.BrowserPoolConfig.Size
+	d.NewSize = d.Size
+	if d.Size > 0 {
+		d.Utilization = float64(d.AcquiredCount) / float64(d.Size)
+	} else if d.AcquiredCount > 0 {
+		d.Utilization = math.Inf(1)
+	}
+
+	// The epsilon keeps rounding errors such as 7/0.7 = 10.000000000000002 from
+	// adding a browser.
+	d.DesiredSize = a.clamp(int64(math.Ceil(float64(d.AcquiredCount)/p.TargetUtilization - 1e-9)))
+	inBounds := d.Size == a.clamp(d.Size)
+	switch {
+	case d.DesiredSize == d.Size:
+		d.Reason = "at desired size"
+		return d
+	case inBounds && math.Abs(d.Utilization-p.TargetUtilization) <= p.Tolerance:
+		d.Reason = "within tolerance"
+		return d
+	}
+
+	newSize := d.DesiredSize
+	since := d.Time.Sub(a.lastResize)
+	if newSize > d.Size {
+		if !a.lastResize.IsZero() && since < p.ScaleUpCooldown {
+			d.Reason = "cooldown"
+			return d
+		}
+		if p.MaxStepUp > 0 {
+			newSize = min(newSize, d.Size+p.MaxStepUp)
+		}
+		if p.OrgLimit > 0 {
+			free, err := a.orgCapacity(ctx, pool.ID, p.OrgLimit)
+			if err != nil {
+				d.Reason, d.Err = "listing pools failed", err
+				return d
+			}
+			if newSize > free {
+				newSize = free
+				d.Reason = "org limit"
+			}
+			if newSize <= d.Size {
+				return d
+			}
+		}
+		d.Action = BrowserPoolScaleActionUp
+	} else {
+		if !a.lastResize.IsZero() && since < p.ScaleDownCooldown {
+			d.Reason = "cooldown"
+			return d
+		}
+		if p.MaxStepDown > 0 {
+			newSize = max(newSize, d.Size-p.MaxStepDown)
+		}
+		d.Action = BrowserPoolScaleActionDown
+	}
+	if d.Reason == "" {
+		d.Reason = fmt.Sprintf("utilization %.2f, target %.2f", d.Utilization, p.TargetUtilization)
+	}
+
+	if _, err := a.pools.Update(ctx, a.pool, BrowserPoolUpdateParams{Size: newSize}, a.opts...); err != nil {
+		d.Action, d.Reason, d.Err = BrowserPoolScaleActionNone, "resize failed", err
+		return d
+	}
+	a.lastResize = d.Time
+	d.NewSize = newSize
+	return d
+}
+
+// clamp bounds a size by the policy's minimum and maximum.
+func (a *BrowserPoolAutoscaler) clamp(size int64) int64 {
+	p := a.params.Policy
+	size = max(size, p.MinSize)
+	if p.MaxSize > 0 {
+		size = min(size, p.MaxSize)
+	}
+	return size
+}
+
+// orgCapacity returns the largest size the pool can have without the sizes of
+// all pools exceeding limit.
+func (a *BrowserPoolAutoscaler) orgCapacity(ctx context.Context, poolID string, limit int64) (int64, error) {
+	pools, err := a.pools.List(ctx, a.opts...)
+	if err != nil {
+		return 0, err
+	}
+	others := int64(0)
+	for _, p := range *pools {
+		if p.ID != poolID {
+			others += p.BrowserPoolConfig.Size
+		}
+	}
+	return limit - others, nil
+}
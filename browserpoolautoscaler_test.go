@@ -0,0 +1,200 @@
+package kernel_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeClockWaiter
+}
+
+type fakeClockWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeClockWaiter{c.now.Add(d), ch})
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiting := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiting = append(waiting, w)
+		} else {
+			w.ch <- c.now
+		}
+	}
+	c.waiters = waiting
+}
+
+func (c *fakeClock) pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+type autoscalerServer struct {
+	mu       sync.Mutex
+	acquired int64
+	size     int64
+	other    int64
+	resizes  []int64
+}
+
+func (s *autoscalerServer) set(acquired int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acquired = acquired
+}
+
+func newAutoscalerServer(t *testing.T, size int64) (*autoscalerServer, kernel.Client) {
+	s := &autoscalerServer{size: size}
+	pool := func(id string, size, acquired int64) string {
+		return fmt.Sprintf(`{"id":%q,"acquired_count":%d,"available_count":%d,"created_at":"2025-01-01T00:00:00Z","browser_pool_config":{"size":%d}}`, id, acquired, size-acquired, size)
+	}
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/browser_pools/pool_1":
+			fmt.Fprint(w, pool("pool_1", s.size, s.acquired))
+		case r.Method == http.MethodGet && r.URL.Path == "/browser_pools":
+			fmt.Fprintf(w, "[%s,%s]", pool("pool_1", s.size, s.acquired), pool("pool_2", s.other, 0))
+		case r.Method == http.MethodPatch && r.URL.Path == "/browser_pools/pool_1":
+			var body struct{ Size int64 }
+			json.NewDecoder(r.Body).Decode(&body)
+			s.size = body.Size
+			s.resizes = append(s.resizes, body.Size)
+			fmt.Fprint(w, pool("pool_1", s.size, s.acquired))
+		default:
+			http.NotFound(w, r)
+		}
+	}), option.WithMaxRetries(0))
+	return s, client
+}
+
+func TestBrowserPoolAutoscalerPolicy(t *testing.T) {
+	s, client := newAutoscalerServer(t, 10)
+	clock := newFakeClock()
+	var decisions []kernel.BrowserPoolScaleDecision
+	scaler := client.BrowserPools.NewAutoscaler("pool_1", kernel.BrowserPoolAutoscalerParams{
+		Policy: kernel.BrowserPoolAutoscalerPolicy{
+			TargetUtilization: 0.5,
+			MinSize:           2,
+			MaxSize:           30,
+			MaxStepDown:       4,
+		},
+		Clock:      clock,
+		OnDecision: func(d kernel.BrowserPoolScaleDecision) { decisions = append(decisions, d) },
+	})
+	ctx := context.Background()
+
+	for _, step := range []struct {
+		acquired int64
+		advance  time.Duration
+		action   kernel.BrowserPoolScaleAction
+		reason   string
+		size     int64
+	}{
+		{5, 0, kernel.BrowserPoolScaleActionNone, "at desired size", 10},
+		{9, 0, kernel.BrowserPoolScaleActionUp, "utilization 0.90, target 0.50", 18},
+		{12, 30 * time.Second, kernel.BrowserPoolScaleActionNone, "cooldown", 18},
+		{12, time.Minute, kernel.BrowserPoolScaleActionUp, "utilization 0.67, target 0.50", 24},
+		{40, time.Minute, kernel.BrowserPoolScaleActionUp, "utilization 1.67, target 0.50", 30},
+		{0, time.Minute, kernel.BrowserPoolScaleActionNone, "cooldown", 30},
+		{0, 5 * time.Minute, kernel.BrowserPoolScaleActionDown, "utilization 0.00, target 0.50", 26},
+	} {
+		s.set(step.acquired)
+		clock.Advance(step.advance)
+		d, err := scaler.Step(ctx)
+		if err != nil {
+			t.Fatalf("err should be nil: %s", err.Error())
+		}
+		if d.Action != step.action || d.Reason != step.reason || d.NewSize != step.size {
+			t.Errorf("with %d acquired: got %s (%s) to %d, want %s (%s) to %d", step.acquired, d.Action, d.Reason, d.NewSize, step.action, step.reason, step.size)
+		}
+	}
+	if fmt.Sprint(s.resizes) != "[18 24 30 26]" || len(decisions) != 7 {
+		t.Errorf("unexpected resizes %v and %d decisions", s.resizes, len(decisions))
+	}
+}
+
+func TestBrowserPoolAutoscalerOrgLimit(t *testing.T) {
+	s, client := newAutoscalerServer(t, 10)
+	s.other = 15
+	s.set(10)
+	scaler := client.BrowserPools.NewAutoscaler("pool_1", kernel.BrowserPoolAutoscalerParams{
+		Policy: kernel.BrowserPoolAutoscalerPolicy{OrgLimit: 20},
+		Clock:  newFakeClock(),
+	})
+	d, err := scaler.Step(context.Background())
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if d.Action != kernel.BrowserPoolScaleActionNone || d.Reason != "org limit" || d.DesiredSize != 15 {
+		t.Errorf("expected the org limit to prevent growth, got %+v", d)
+	}
+	s.other = 5
+	if d, _ := scaler.Step(context.Background()); d.NewSize != 15 {
+		t.Errorf("expected the pool to grow once capacity is free, got %+v", d)
+	}
+}
+
+func TestBrowserPoolAutoscalerRun(t *testing.T) {
+	s, client := newAutoscalerServer(t, 10)
+	clock := newFakeClock()
+	decided := make(chan kernel.BrowserPoolScaleDecision)
+	scaler := client.BrowserPools.NewAutoscaler("pool_1", kernel.BrowserPoolAutoscalerParams{
+		Interval:   time.Minute,
+		Clock:      clock,
+		OnDecision: func(d kernel.BrowserPoolScaleDecision) { decided <- d },
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- scaler.Run(ctx) }()
+
+	<-decided
+	s.set(14)
+	for clock.pending() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(time.Minute)
+	if d := <-decided; d.Action != kernel.BrowserPoolScaleActionUp || d.NewSize != 20 {
+		t.Errorf("expected the second sample to grow the pool, got %+v", d)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/option"
+	"github.com/kernel/kernel-go-sdk/packages/param"
+)
+
+// BrowserPoolLeaseParams configures [BrowserPoolService.Lease].
+type BrowserPoolLeaseParams struct {
+	// Maximum number of seconds each acquire request waits for a browser. It is
+	// capped to the time left before the context's deadline, so a browser is never
+	// acquired after the caller has given up on it.
+	AcquireTimeoutSeconds param.Opt[int64]
+	// Base delay before retrying when no browser was available or the request
+	// failed transiently, doubled on each retry and capped at 30 seconds. Defaults
+	// to 1 second.
+	Backoff time.Duration
+}
+
+// BrowserPoolLease is a browser acquired from a pool by
+// [BrowserPoolService.Lease]. It embeds the browser's [Session]. Close the lease
+// to release the browser back to the pool.
+type BrowserPoolLease struct {
+	*Session
+	// ID or name of the pool the browser was acquired from.
+	Pool       string
+	AcquiredAt time.Time
+
+	pools      *BrowserPoolService
+	opts       []option.RequestOption
+	acquiredBy string
+
+	mu          sync.Mutex
+	notReusable bool
+	closeOnce   sync.Once
+	closeErr    error
+}
+
+// MarkNotReusable makes Close destroy the browser instead of returning it to the
+// pool for reuse.
+func (l *BrowserPoolLease) MarkNotReusable() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.notReusable = true
+}
+
+// Close releases the browser back to the pool. It is safe to call more than
+// once; later calls return the result of the first. The release is bounded by
+// [BrowserSessionCleanupTimeout].
+func (l *BrowserPoolLease) Close() error {
+	l.closeOnce.Do(func() {
+		openLeases.remove(l)
+		body := BrowserPoolReleaseParams{SessionID: l.ID()}
+		l.mu.Lock()
+		if l.notReusable {
+			body.Reuse = Bool(false)
+		}
+		l.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), BrowserSessionCleanupTimeout)
+		defer cancel()
+		if err := l.pools.Release(ctx, l.Pool, body, l.opts...); err != nil {
+			l.closeErr = fmt.Errorf("kernel: releasing browser %s to pool %s: %w", l.ID(), l.Pool, err)
+		}
+	})
+	return l.closeErr
+}
+
+// CloseWithError releases the browser like Close, but destroys it instead of
+// returning it for reuse if err is not nil, so a browser left in a bad state by
+// a failed task is not handed out again:
+//
+//	defer func() { lease.CloseWithError(err) }()
+func (l *BrowserPoolLease) CloseWithError(err error) error {
+	if err != nil {
+		l.MarkNotReusable()
+	}
+	return l.Close()
+}
+
+// Lease acquires a browser from the pool, waiting until one is available. When
+// none is, it retries with backoff until ctx is done. Every lease must be
+// closed; [BrowserPoolService.LeaksReport] lists the ones that are not.
+func (r *BrowserPoolService) Lease(ctx context.Context, idOrName string, params BrowserPoolLeaseParams, opts ...option.RequestOption) (res *BrowserPoolLease, err error) {
+	if params.Backoff <= 0 {
+		params.Backoff = time.Second
+	}
+	acquiredBy := leaseCaller()
+	session, err := r.acquireWithBackoff(ctx, idOrName, params.AcquireTimeoutSeconds, params.Backoff, opts)
+	if err != nil {
+		return nil, err
+	}
+	return r.newLease(session, idOrName, acquiredBy, opts), nil
+}
+
+// acquireWithBackoff polls the pool until a browser is acquired, waiting with
+// exponential backoff from backoff between polls. Transient errors are retried
+// too.
+func (r *BrowserPoolService) acquireWithBackoff(ctx context.Context, idOrName string, timeout param.Opt[int64], backoff time.Duration, opts []option.RequestOption) (*Session, error) {
+	for attempt := 1; ; attempt++ {
+		session, err := r.tryAcquire(ctx, idOrName, timeout, opts)
+		switch {
+		case session != nil:
+			return session, nil
+		case err != nil && (ctx.Err() != nil || !isTransientError(err)):
+			return nil, err
+		}
+		if err := sleepContext(ctx, retryBackoff(backoff, attempt)); err != nil {
+			return nil, fmt.Errorf("kernel: no browser available in pool %s: %w", idOrName, err)
+		}
+	}
+}
+
+// tryAcquire makes a single acquire request, with the timeout capped to ctx's
+// deadline. It returns a nil session and a nil error if no browser was
+// available.
+func (r *BrowserPoolService) tryAcquire(ctx context.Context, idOrName string, timeout param.Opt[int64], opts []option.RequestOption) (*Session, error) {
+	body := BrowserPoolAcquireParams{AcquireTimeoutSeconds: timeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		left := int64(time.Until(deadline) / time.Second)
+		if left < 1 {
+			return nil, fmt.Errorf("kernel: no browser available in pool %s: %w", idOrName, context.DeadlineExceeded)
+		}
+		if !body.AcquireTimeoutSeconds.Valid() || body.AcquireTimeoutSeconds.Value > left {
+			body.AcquireTimeoutSeconds = Int(left)
+		}
+	}
+
+	var raw *http.Response
+	browser, err := r.Acquire(ctx, idOrName, body, slices.Concat(opts, []option.RequestOption{option.WithResponseInto(&raw)})...)
+	if raw != nil && raw.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	browsers := NewBrowserService(r.Options...)
+	return newSession(&browsers, BrowserNewResponse(*browser)), nil
+}
+
+// newLease tracks session, acquired from pool, as a lease.
+func (r *BrowserPoolService) newLease(session *Session, pool, acquiredBy string, opts []option.RequestOption) *BrowserPoolLease {
+	lease := &BrowserPoolLease{
+		Session:    session,
+		Pool:       pool,
+		AcquiredAt: time.Now(),
+		pools:      r,
+		opts:       opts,
+		acquiredBy: acquiredBy,
+	}
+	openLeases.add(lease)
+	return lease
+}
+
+// leaseCaller returns the file and line that called the exported function
+// calling it.
+func leaseCaller() string {
+	if _, file, line, ok := runtime.Caller(2); ok {
+		return fmt.Sprintf("%s:%d", file, line)
+	}
+	return "unknown"
+}
+
+// BrowserPoolLeak is a lease that has not been closed.
+type BrowserPoolLeak struct {
+	Pool       string
+	SessionID  string
+	AcquiredAt time.Time
+	// File and line of the call to Lease.
+	AcquiredBy string
+}
+
+func (l BrowserPoolLeak) String() string {
+	return fmt.Sprintf("browser %s from pool %s leased at %s by %s", l.SessionID, l.Pool, l.AcquiredAt.Format(time.RFC3339), l.AcquiredBy)
+}
+
+// LeaksReport lists the leases made through this client that have not been
+// closed, oldest first. It is meant for tests:
+//
+//	t.Cleanup(func() {
+//		for _, leak := range client.BrowserPools.LeaksReport() {
+//			t.Errorf("leaked %s", leak)
+//		}
+//	})
+func (r *BrowserPoolService) LeaksReport() []BrowserPoolLeak {
+	return openLeases.report(r)
+}
+
+// openLeases tracks the leases of every client that have not been closed.
+var openLeases browserPoolLeases
+
+type browserPoolLeases struct {
+	mu   sync.Mutex
+	open map[*BrowserPoolLease]struct{}
+}
+
+func (t *browserPoolLeases) add(l *BrowserPoolLease) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.open == nil {
+		t.open = map[*BrowserPoolLease]struct{}{}
+	}
+	t.open[l] = struct{}{}
+}
+
+func (t *browserPoolLeases) remove(l *BrowserPoolLease) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.open, l)
+}
+
+// report lists the open leases made through pools.
+func (t *browserPoolLeases) report(pools *BrowserPoolService) []BrowserPoolLeak {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var leaks []BrowserPoolLeak
+	for l := range t.open {
+		if l.pools != pools {
+			continue
+		}
+		leaks = append(leaks, BrowserPoolLeak{Pool: l.Pool, SessionID: l.ID(), AcquiredAt: l.AcquiredAt, AcquiredBy: l.acquiredBy})
+	}
+	sort.Slice(leaks, func(i, j int) bool { return leaks[i].AcquiredAt.Before(leaks[j].AcquiredAt) })
+	return leaks
+}
@@ -0,0 +1,155 @@
+package kernel_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+type leaseServer struct {
+	mu       sync.Mutex
+	busy     int
+	timeouts []string
+	released []string
+}
+
+func newLeaseServer(t *testing.T, busy int) (*leaseServer, kernel.Client) {
+	s := &leaseServer{busy: busy}
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/browser_pools/pool/acquire":
+			s.timeouts = append(s.timeouts, fmt.Sprint(body["acquire_timeout_seconds"]))
+			if s.busy > 0 {
+				s.busy--
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"session_id":"sess_1","cdp_ws_url":"wss://cdp","created_at":"2025-01-01T00:00:00Z","headless":true,"stealth":false,"timeout_seconds":60}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/browser_pools/pool/release":
+			s.released = append(s.released, fmt.Sprintf("%v reuse=%v", body["session_id"], body["reuse"]))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}), option.WithMaxRetries(0))
+	return s, client
+}
+
+func TestBrowserPoolLease(t *testing.T) {
+	s, client := newLeaseServer(t, 2)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	lease, err := client.BrowserPools.Lease(ctx, "pool", kernel.BrowserPoolLeaseParams{
+		AcquireTimeoutSeconds: kernel.Int(120),
+		Backoff:               time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if lease.ID() != "sess_1" || lease.Pool != "pool" {
+		t.Errorf("unexpected lease %s from %s", lease.ID(), lease.Pool)
+	}
+	if len(s.timeouts) != 3 || s.timeouts[0] == "120" {
+		t.Errorf("expected three attempts with the timeout capped to the deadline, got %v", s.timeouts)
+	}
+
+	leaks := client.BrowserPools.LeaksReport()
+	if len(leaks) != 1 || leaks[0].SessionID != "sess_1" || !strings.Contains(leaks[0].AcquiredBy, "browserpoollease_test.go") {
+		t.Errorf("expected the open lease to be reported, got %v", leaks)
+	}
+
+	if err := lease.CloseWithError(errors.New("page crashed")); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	lease.Close()
+	if fmt.Sprint(s.released) != "[sess_1 reuse=false]" {
+		t.Errorf("expected a single release without reuse, got %v", s.released)
+	}
+	if leaks := client.BrowserPools.LeaksReport(); len(leaks) != 0 {
+		t.Errorf("expected no leaks after Close, got %v", leaks)
+	}
+}
+
+func TestBrowserPoolLeaseReuse(t *testing.T) {
+	s, client := newLeaseServer(t, 0)
+	lease, err := client.BrowserPools.Lease(context.Background(), "pool", kernel.BrowserPoolLeaseParams{})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if s.timeouts[0] != "<nil>" {
+		t.Errorf("expected no acquire timeout without a deadline, got %v", s.timeouts)
+	}
+	lease.Close()
+	if fmt.Sprint(s.released) != "[sess_1 reuse=<nil>]" {
+		t.Errorf("expected the browser to be released for reuse, got %v", s.released)
+	}
+}
+
+func TestBrowserPoolLeaseDeadline(t *testing.T) {
+	_, client := newLeaseServer(t, 1000)
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	_, err := client.BrowserPools.Lease(ctx, "pool", kernel.BrowserPoolLeaseParams{Backoff: 100 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be exceeded, got %v", err)
+	}
+	if leaks := client.BrowserPools.LeaksReport(); len(leaks) != 0 {
+		t.Errorf("expected no leases, got %v", leaks)
+	}
+}
+
+func TestBrowserPoolLeaseCloseDoesNotBlock(t *testing.T) {
+	releasing := make(chan struct{})
+	release := make(chan struct{})
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/browser_pools/pool/acquire":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"session_id":"sess_1","cdp_ws_url":"wss://cdp","created_at":"2025-01-01T00:00:00Z","headless":true,"stealth":false,"timeout_seconds":60}`)
+		case "/browser_pools/pool/release":
+			close(releasing)
+			<-release
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}), option.WithMaxRetries(0))
+	lease, err := client.BrowserPools.Lease(context.Background(), "pool", kernel.BrowserPoolLeaseParams{})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+
+	closed := make(chan error)
+	go func() { closed <- lease.Close() }()
+	<-releasing
+	marked := make(chan struct{})
+	go func() {
+		lease.MarkNotReusable()
+		close(marked)
+	}()
+	select {
+	case <-marked:
+	case <-time.After(time.Second):
+		t.Error("expected MarkNotReusable not to wait for the release")
+	}
+	close(release)
+	if err := <-closed; err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if err := lease.Close(); err != nil {
+		t.Errorf("expected a second Close to return the first result, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/option"
+	"github.com/kernel/kernel-go-sdk/packages/param"
+)
+
+// How [BrowserPoolService.LeaseAny] orders the pools it acquires from.
+type BrowserPoolStrategy string
+
+const (
+	// Pools are tried in the order they are listed.
+	BrowserPoolStrategyPriority BrowserPoolStrategy = "priority"
+	// Pools are tried in a random order in which each pool's chance of coming
+	// first is proportional to its weight.
+	BrowserPoolStrategyWeighted BrowserPoolStrategy = "weighted"
+)
+
+// BrowserPoolChoice is a pool [BrowserPoolService.LeaseAny] may acquire from.
+type BrowserPoolChoice struct {
+	// ID or name of the pool.
+	Pool string
+	// Relative share of leases with [BrowserPoolStrategyWeighted]. Defaults to 1.
+	// It is ignored by [BrowserPoolStrategyPriority].
+	Weight float64
+}
+
+// BrowserPoolLeaseAnyParams configures [BrowserPoolService.LeaseAny].
+type BrowserPoolLeaseAnyParams struct {
+	// Pools to acquire from, most preferred first.
+	Pools []BrowserPoolChoice
+	// Defaults to [BrowserPoolStrategyPriority].
+	Strategy BrowserPoolStrategy
+	// Maximum number of seconds each acquire request waits for a browser before
+	// falling back to the next pool. It is capped to the time left before the
+	// context's deadline. Defaults to 1 second.
+	AcquireTimeoutSeconds param.Opt[int64]
+	// Base delay before trying all the pools again when none had a browser,
+	// doubled on each retry and capped at 30 seconds. Defaults to 1 second.
+	Backoff time.Duration
+}
+
+// LeaseAny acquires a browser from one of several pools, falling back to the
+// next pool when one is exhausted. Before each pass over the pools, their
+// available counts are fetched, and the pools with idle browsers are tried
+// before the others, in the order of the strategy. When no pool has a browser,
+// it retries with backoff until ctx is done.
+//
+// The lease's Pool field is the pool that served it, and closing the lease
+// releases the browser to that pool.
+func (r *BrowserPoolService) LeaseAny(ctx context.Context, params BrowserPoolLeaseAnyParams, opts ...option.RequestOption) (res *BrowserPoolLease, err error) {
+	if len(params.Pools) == 0 {
+		return nil, errors.New("kernel: no pools to lease from")
+	}
+	switch params.Strategy {
+	case "":
+		params.Strategy = BrowserPoolStrategyPriority
+	case BrowserPoolStrategyPriority, BrowserPoolStrategyWeighted:
+	default:
+		return nil, fmt.Errorf("kernel: unknown browser pool strategy %q", params.Strategy)
+	}
+	params.Pools = append([]BrowserPoolChoice(nil), params.Pools...)
+	for i, c := range params.Pools {
+		if c.Weight < 0 || math.IsNaN(c.Weight) || math.IsInf(c.Weight, 0) {
+			return nil, fmt.Errorf("kernel: invalid weight %v for pool %s", c.Weight, c.Pool)
+		}
+		if c.Weight == 0 {
+			params.Pools[i].Weight = 1
+		}
+	}
+	if !params.AcquireTimeoutSeconds.Valid() {
+		params.AcquireTimeoutSeconds = Int(1)
+	}
+	if params.Backoff <= 0 {
+		params.Backoff = time.Second
+	}
+	acquiredBy := leaseCaller()
+
+	for attempt := 1; ; attempt++ {
+		for _, pool := range r.rankPools(ctx, params, opts) {
+			session, err := r.tryAcquire(ctx, pool, params.AcquireTimeoutSeconds, opts)
+			switch {
+			case session != nil:
+				return r.newLease(session, pool, acquiredBy, opts), nil
+			case errors.Is(err, context.DeadlineExceeded):
+				return nil, fmt.Errorf("kernel: no browser available in pools %s: %w", browserPoolNames(params.Pools), context.DeadlineExceeded)
+			case err != nil && (ctx.Err() != nil || !isTransientError(err)):
+				return nil, err
+			}
+		}
+		if err := sleepContext(ctx, retryBackoff(params.Backoff, attempt)); err != nil {
+			return nil, fmt.Errorf("kernel: no browser available in pools %s: %w", browserPoolNames(params.Pools), err)
+		}
+	}
+}
+
+// rankPools returns the names of the pools in the order they should be tried.
+// A pool whose available count cannot be fetched is treated as having no idle
+// browsers; the acquire request reports the actual problem.
+func (r *BrowserPoolService) rankPools(ctx context.Context, params BrowserPoolLeaseAnyParams, opts []option.RequestOption) []string {
+	available := make([]int64, len(params.Pools))
+	var wg sync.WaitGroup
+	for i, c := range params.Pools {
+		wg.Add(1)
+		go func(i int, pool string) {
+			defer wg.Done()
+			if p, err := r.Get(ctx, pool, opts...); err == nil {
+				available[i] = p.AvailableCount
+			}
+		}(i, c.Pool)
+	}
+	wg.Wait()
+
+	// With weights w, sorting by u^(1/w) for uniform u gives each pool a chance
+	// proportional to its weight of being first among the remaining ones.
+	keys := make([]float64, len(params.Pools))
+	if params.Strategy == BrowserPoolStrategyWeighted {
+		for i, c := range params.Pools {
+			keys[i] = math.Pow(rand.Float64(), 1/c.Weight)
+		}
+	}
+	order := make([]int, len(params.Pools))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		if (available[i] > 0) != (available[j] > 0) {
+			return available[i] > 0
+		}
+		return keys[i] > keys[j]
+	})
+	pools := make([]string, len(order))
+	for n, i := range order {
+		pools[n] = params.Pools[i].Pool
+	}
+	return pools
+}
+
+func browserPoolNames(choices []BrowserPoolChoice) string {
+	names := make([]string, len(choices))
+	for i, c := range choices {
+		names[i] = c.Pool
+	}
+	return strings.Join(names, ", ")
+}
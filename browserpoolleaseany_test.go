@@ -0,0 +1,128 @@
+package kernel_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// newLeaseAnyServer serves pools with the given available counts. Acquiring
+// from a pool with no available browsers returns 204.
+func newLeaseAnyServer(t *testing.T, available map[string]int) (*[]string, kernel.Client) {
+	var mu sync.Mutex
+	var calls []string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		pool, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/browser_pools/"), "/")
+		n, ok := available[pool]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch action {
+		case "":
+			fmt.Fprintf(w, `{"id":"%s","name":"%s","acquired_count":0,"available_count":%d,"created_at":"2025-01-01T00:00:00Z","browser_pool_config":{"size":2}}`, pool, pool, n)
+		case "acquire":
+			calls = append(calls, "acquire "+pool)
+			if n == 0 {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			available[pool]--
+			fmt.Fprintf(w, `{"session_id":"sess_%s","cdp_ws_url":"wss://cdp","created_at":"2025-01-01T00:00:00Z","headless":true,"stealth":false,"timeout_seconds":60}`, pool)
+		case "release":
+			calls = append(calls, "release "+pool)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}), option.WithMaxRetries(0))
+	return &calls, client
+}
+
+func TestBrowserPoolLeaseAnyFallback(t *testing.T) {
+	calls, client := newLeaseAnyServer(t, map[string]int{"stealth": 0, "plain": 1})
+	lease, err := client.BrowserPools.LeaseAny(context.Background(), kernel.BrowserPoolLeaseAnyParams{
+		Pools: []kernel.BrowserPoolChoice{{Pool: "stealth"}, {Pool: "plain"}},
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if lease.Pool != "plain" || lease.ID() != "sess_plain" {
+		t.Errorf("expected a lease from the fallback pool, got %s from %s", lease.ID(), lease.Pool)
+	}
+	lease.Close()
+	if fmt.Sprint(*calls) != "[acquire plain release plain]" {
+		t.Errorf("expected the exhausted pool to be skipped and the browser released to its pool, got %v", *calls)
+	}
+}
+
+func TestBrowserPoolLeaseAnyPriority(t *testing.T) {
+	calls, client := newLeaseAnyServer(t, map[string]int{"stealth": 1, "plain": 1})
+	lease, err := client.BrowserPools.LeaseAny(context.Background(), kernel.BrowserPoolLeaseAnyParams{
+		Pools:    []kernel.BrowserPoolChoice{{Pool: "stealth"}, {Pool: "plain"}},
+		Strategy: kernel.BrowserPoolStrategyPriority,
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	defer lease.Close()
+	if lease.Pool != "stealth" || fmt.Sprint(*calls) != "[acquire stealth]" {
+		t.Errorf("expected a lease from the preferred pool, got %s after %v", lease.Pool, *calls)
+	}
+}
+
+func TestBrowserPoolLeaseAnyWeighted(t *testing.T) {
+	served := map[string]int{}
+	for i := 0; i < 200; i++ {
+		_, client := newLeaseAnyServer(t, map[string]int{"a": 1, "b": 1})
+		lease, err := client.BrowserPools.LeaseAny(context.Background(), kernel.BrowserPoolLeaseAnyParams{
+			Pools:    []kernel.BrowserPoolChoice{{Pool: "a", Weight: 9}, {Pool: "b"}},
+			Strategy: kernel.BrowserPoolStrategyWeighted,
+		})
+		if err != nil {
+			t.Fatalf("err should be nil: %s", err.Error())
+		}
+		served[lease.Pool]++
+		lease.Close()
+	}
+	if served["a"] < 140 || served["b"] == 0 {
+		t.Errorf("expected about 9 in 10 leases from the heavier pool, got %v", served)
+	}
+}
+
+func TestBrowserPoolLeaseAnyExhausted(t *testing.T) {
+	calls, client := newLeaseAnyServer(t, map[string]int{"stealth": 0, "plain": 0})
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	_, err := client.BrowserPools.LeaseAny(ctx, kernel.BrowserPoolLeaseAnyParams{
+		Pools:   []kernel.BrowserPoolChoice{{Pool: "stealth"}, {Pool: "plain"}},
+		Backoff: 100 * time.Millisecond,
+	})
+	if err == nil || !strings.Contains(err.Error(), "stealth, plain") {
+		t.Errorf("expected no browser to be available, got %v", err)
+	}
+	if len(*calls) < 4 || (*calls)[0] != "acquire stealth" || (*calls)[1] != "acquire plain" {
+		t.Errorf("expected every pool to be retried in priority order, got %v", *calls)
+	}
+	if leaks := client.BrowserPools.LeaksReport(); len(leaks) != 0 {
+		t.Errorf("expected no leases, got %v", leaks)
+	}
+}
+
+func TestBrowserPoolLeaseAnyUnknownPool(t *testing.T) {
+	_, client := newLeaseAnyServer(t, map[string]int{"plain": 0})
+	_, err := client.BrowserPools.LeaseAny(context.Background(), kernel.BrowserPoolLeaseAnyParams{
+		Pools: []kernel.BrowserPoolChoice{{Pool: "missing"}, {Pool: "plain"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected the unknown pool to fail the lease, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/option"
+	"github.com/kernel/kernel-go-sdk/packages/param"
+)
+
+// BrowserReapParams selects the browser sessions deleted by
+// [BrowserService.Reap]. A session must match every filter that is set. Setting
+// no filter is an error unless All is true, so a zero value never reaps every
+// session by accident.
+type BrowserReapParams struct {
+	// Only sessions created at least this long ago.
+	OlderThan time.Duration
+	// Only sessions with this headless flag.
+	Headless param.Opt[bool]
+	// Only sessions with this stealth flag.
+	Stealth param.Opt[bool]
+	// Only sessions using the proxy with this ID.
+	ProxyID string
+	// Only sessions using the profile with this name or ID.
+	Profile string
+	// Only sessions created within this invocation. Session listings do not record
+	// their invocation, so this cannot be combined with the other filters or
+	// DryRun: the sessions are deleted with [InvocationService.DeleteBrowsers] and
+	// the report lists the invocation instead of individual sessions.
+	InvocationID string
+	// Only sessions for which Match returns true.
+	Match func(BrowserListResponse) bool
+	// If true and no other filter is set, every active session matches.
+	All bool
+	// If true, nothing is deleted and the report lists the matching sessions.
+	DryRun bool
+	// Maximum number of deletions in flight at once. Defaults to 4.
+	Concurrency int
+	// Maximum number of deletions started per second. Zero means unlimited.
+	RatePerSecond float64
+}
+
+func (p BrowserReapParams) hasSessionFilters() bool {
+	return p.OlderThan > 0 || p.Headless.Valid() || p.Stealth.Valid() || p.ProxyID != "" || p.Profile != "" || p.Match != nil
+}
+
+func (p BrowserReapParams) matches(b BrowserListResponse, now time.Time) bool {
+	switch {
+	case p.OlderThan > 0 && now.Sub(b.CreatedAt) < p.OlderThan:
+		return false
+	case p.Headless.Valid() && b.Headless != p.Headless.Value:
+		return false
+	case p.Stealth.Valid() && b.Stealth != p.Stealth.Value:
+		return false
+	case p.ProxyID != "" && b.ProxyID != p.ProxyID:
+		return false
+	case p.Profile != "" && b.Profile.Name != p.Profile && b.Profile.ID != p.Profile:
+		return false
+	case p.Match != nil && !p.Match(b):
+		return false
+	}
+	return true
+}
+
+// BrowserReapFailure is a session that could not be deleted.
+type BrowserReapFailure struct {
+	SessionID string
+	Err       error
+}
+
+// BrowserReapReport is the outcome of [BrowserService.Reap].
+type BrowserReapReport struct {
+	// Number of active sessions listed.
+	Scanned int
+	// Sessions that matched the filters, in listing order.
+	Matched []BrowserListResponse
+	// IDs of the sessions that were deleted, or that are already gone. Empty for a
+	// dry run.
+	Deleted []string
+	// Sessions whose deletion failed.
+	Failed []BrowserReapFailure
+	// Invocation whose sessions were deleted, when filtering by invocation.
+	InvocationID string
+	DryRun       bool
+}
+
+// Reap deletes the active browser sessions matching params. Every page of
+// sessions is listed before anything is deleted, so deletions do not shift the
+// pages being read. Deletions run concurrently, bounded by params.Concurrency and
+// params.RatePerSecond, and a failure does not stop the others; the report lists
+// every failure and the returned error joins them. On cancellation the partial
+// report is returned with the context's error.
+func (r *BrowserService) Reap(ctx context.Context, params BrowserReapParams, opts ...option.RequestOption) (res *BrowserReapReport, err error) {
+	res = &BrowserReapReport{DryRun: params.DryRun}
+	if params.InvocationID != "" {
+		if params.hasSessionFilters() || params.All || params.DryRun {
+			return nil, errors.New("kernel: reaping by invocation cannot be combined with other filters or a dry run")
+		}
+		invocations := NewInvocationService(r.Options...)
+		if err := invocations.DeleteBrowsers(ctx, params.InvocationID, opts...); err != nil {
+			return nil, err
+		}
+		res.InvocationID = params.InvocationID
+		return res, nil
+	}
+	if !params.hasSessionFilters() && !params.All {
+		return nil, errors.New("kernel: no reap filter set; set All to reap every active session")
+	}
+	if params.Concurrency <= 0 {
+		params.Concurrency = 4
+	}
+
+	now := time.Now()
+	iter := r.ListAutoPaging(ctx, BrowserListParams{Status: BrowserListParamsStatusActive}, opts...)
+	for iter.Next() {
+		res.Scanned++
+		if b := iter.Current(); params.matches(b, now) {
+			res.Matched = append(res.Matched, b)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return res, err
+	}
+	if params.DryRun {
+		return res, nil
+	}
+
+	limiter := newRateLimiter(params.RatePerSecond)
+	ids := make(chan string)
+	var mu sync.Mutex
+	var workers sync.WaitGroup
+	for i := 0; i < params.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for id := range ids {
+				if err := limiter.wait(ctx); err != nil {
+					return
+				}
+				err := r.DeleteByID(ctx, id, opts...)
+				if isNotFound(err) {
+					err = nil
+				}
+				mu.Lock()
+				if err != nil {
+					res.Failed = append(res.Failed, BrowserReapFailure{SessionID: id, Err: err})
+				} else {
+					res.Deleted = append(res.Deleted, id)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+send:
+	for _, b := range res.Matched {
+		select {
+		case ids <- b.SessionID:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(ids)
+	workers.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	errs := make([]error, len(res.Failed))
+	for i, f := range res.Failed {
+		errs[i] = fmt.Errorf("kernel: deleting browser session %s: %w", f.SessionID, f.Err)
+	}
+	return res, errors.Join(errs...)
+}
@@ -0,0 +1,149 @@
+package kernel_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+func newReapServer(t *testing.T) (*[]string, kernel.Client) {
+	old := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	recent := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	sessions := []string{
+		fmt.Sprintf(`{"session_id":"old_headless","cdp_ws_url":"wss://cdp","created_at":%q,"headless":true,"stealth":false,"timeout_seconds":60}`, old),
+		fmt.Sprintf(`{"session_id":"old_stealth","cdp_ws_url":"wss://cdp","created_at":%q,"headless":false,"stealth":true,"timeout_seconds":60,"proxy_id":"proxy_1","profile":{"id":"prof_1","name":"shopper","created_at":%q}}`, old, old),
+		fmt.Sprintf(`{"session_id":"recent","cdp_ws_url":"wss://cdp","created_at":%q,"headless":true,"stealth":true,"timeout_seconds":60,"proxy_id":"proxy_1"}`, recent),
+		fmt.Sprintf(`{"session_id":"broken","cdp_ws_url":"wss://cdp","created_at":%q,"headless":true,"stealth":false,"timeout_seconds":60}`, old),
+	}
+	var mu sync.Mutex
+	deleted := []string{}
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/browsers":
+			if r.URL.Query().Get("status") != "active" {
+				t.Errorf("expected only active sessions to be listed, got %q", r.URL.RawQuery)
+			}
+			// Two sessions per page.
+			offset := 0
+			fmt.Sscan(r.URL.Query().Get("offset"), &offset)
+			end := min(offset+2, len(sessions))
+			next := "0"
+			if end < len(sessions) {
+				next = fmt.Sprint(end)
+			}
+			w.Header().Set("X-Next-Offset", next)
+			fmt.Fprintf(w, "[%s]", strings.Join(sessions[offset:end], ","))
+		case r.Method == http.MethodDelete && r.URL.Path == "/browsers/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"code":"internal","message":"boom"}`)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/browsers/"):
+			mu.Lock()
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/browsers/"))
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete && r.URL.Path == "/invocations/inv_1/browsers":
+			mu.Lock()
+			deleted = append(deleted, "invocation inv_1")
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}), option.WithMaxRetries(0))
+	return &deleted, client
+}
+
+func matchedIDs(report *kernel.BrowserReapReport) []string {
+	var ids []string
+	for _, b := range report.Matched {
+		ids = append(ids, b.SessionID)
+	}
+	return ids
+}
+
+func TestBrowserReapDryRun(t *testing.T) {
+	deleted, client := newReapServer(t)
+	for name, tc := range map[string]struct {
+		params kernel.BrowserReapParams
+		want   string
+	}{
+		"all":      {kernel.BrowserReapParams{All: true}, "[old_headless old_stealth recent broken]"},
+		"age":      {kernel.BrowserReapParams{OlderThan: time.Hour}, "[old_headless old_stealth broken]"},
+		"headless": {kernel.BrowserReapParams{Headless: kernel.Bool(false)}, "[old_stealth]"},
+		"stealth":  {kernel.BrowserReapParams{Stealth: kernel.Bool(true), OlderThan: time.Hour}, "[old_stealth]"},
+		"proxy":    {kernel.BrowserReapParams{ProxyID: "proxy_1"}, "[old_stealth recent]"},
+		"profile":  {kernel.BrowserReapParams{Profile: "shopper"}, "[old_stealth]"},
+		"match": {kernel.BrowserReapParams{Match: func(b kernel.BrowserListResponse) bool {
+			return strings.HasPrefix(b.SessionID, "old")
+		}}, "[old_headless old_stealth]"},
+	} {
+		tc.params.DryRun = true
+		report, err := client.Browsers.Reap(context.Background(), tc.params)
+		if err != nil {
+			t.Fatalf("%s: err should be nil: %s", name, err.Error())
+		}
+		if got := fmt.Sprint(matchedIDs(report)); got != tc.want || report.Scanned != 4 || !report.DryRun {
+			t.Errorf("%s: matched %s of %d, want %s", name, got, report.Scanned, tc.want)
+		}
+	}
+	if len(*deleted) != 0 {
+		t.Errorf("expected a dry run not to delete anything, deleted %v", *deleted)
+	}
+}
+
+func TestBrowserReap(t *testing.T) {
+	deleted, client := newReapServer(t)
+	report, err := client.Browsers.Reap(context.Background(), kernel.BrowserReapParams{
+		OlderThan:     time.Hour,
+		Concurrency:   2,
+		RatePerSecond: 1000,
+	})
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("expected the failed deletion to be reported, got %v", err)
+	}
+	sort.Strings(report.Deleted)
+	if fmt.Sprint(report.Deleted) != "[old_headless old_stealth]" || len(report.Failed) != 1 || report.Failed[0].SessionID != "broken" {
+		t.Errorf("unexpected report: deleted %v, failed %v", report.Deleted, report.Failed)
+	}
+	if len(*deleted) != 2 {
+		t.Errorf("expected two deletions, got %v", *deleted)
+	}
+}
+
+func TestBrowserReapRequiresFilter(t *testing.T) {
+	deleted, client := newReapServer(t)
+	for _, params := range []kernel.BrowserReapParams{{}, {DryRun: true}, {Concurrency: 2}} {
+		if _, err := client.Browsers.Reap(context.Background(), params); err == nil || !strings.Contains(err.Error(), "no reap filter") {
+			t.Errorf("expected %+v to be refused, got %v", params, err)
+		}
+	}
+	if _, err := client.Browsers.Reap(context.Background(), kernel.BrowserReapParams{InvocationID: "inv_1", All: true}); err == nil {
+		t.Error("expected All to be refused when reaping by invocation")
+	}
+	if len(*deleted) != 0 {
+		t.Errorf("expected nothing to be deleted, deleted %v", *deleted)
+	}
+}
+
+func TestBrowserReapInvocation(t *testing.T) {
+	deleted, client := newReapServer(t)
+	if _, err := client.Browsers.Reap(context.Background(), kernel.BrowserReapParams{InvocationID: "inv_1", DryRun: true}); err == nil {
+		t.Error("expected an error for a dry run by invocation")
+	}
+	report, err := client.Browsers.Reap(context.Background(), kernel.BrowserReapParams{InvocationID: "inv_1"})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if report.InvocationID != "inv_1" || fmt.Sprint(*deleted) != "[invocation inv_1]" {
+		t.Errorf("expected the invocation's browsers to be deleted, got %v", *deleted)
+	}
+}
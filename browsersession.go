@@ -0,0 +1,347 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/option"
+	"github.com/kernel/kernel-go-sdk/packages/ssestream"
+	"github.com/kernel/kernel-go-sdk/shared"
+)
+
+// BrowserSessionCleanupTimeout bounds how long [BrowserService.With] waits for a
+// session to be deleted once the callback has returned or its context has been
+// cancelled.
+const BrowserSessionCleanupTimeout = 30 * time.Second
+
+// Session is a browser session bound to its ID. Its fields mirror the
+// sub-services of [BrowserService] without the session ID argument, and its
+// methods cover the most common operations. Sessions are returned by
+// [BrowserService.NewSession], [BrowserService.GetSession] and
+// [BrowserPoolService.AcquireSession].
+type Session struct {
+	Computer   BrowserSessionComputer
+	Fs         BrowserSessionFs
+	Process    BrowserSessionProcess
+	Playwright BrowserSessionPlaywright
+	Replays    BrowserSessionReplays
+	Logs       BrowserSessionLogs
+
+	browsers *BrowserService
+	id       string
+
+	// browserMu guards browser, which Refresh replaces while a keepalive or
+	// another caller may be reading it.
+	browserMu     sync.RWMutex
+	browser       BrowserNewResponse
+	keepaliveMu   sync.Mutex
+	stopKeepalive context.CancelFunc
+}
+
+// BrowserSession is the [Session] passed to [BrowserService.With].
+type BrowserSession = Session
+
+// ID returns the session ID.
+func (s *Session) ID() string { return s.id }
+
+// Browser returns the session's metadata, including CdpWsURL, BrowserLiveViewURL
+// and Viewport, as of its creation or the last [Session.Refresh].
+func (s *Session) Browser() BrowserNewResponse {
+	s.browserMu.RLock()
+	defer s.browserMu.RUnlock()
+	return s.browser
+}
+
+func newSession(r *BrowserService, browser BrowserNewResponse) *Session {
+	id := browser.SessionID
+	return &Session{
+		browsers:   r,
+		id:         id,
+		browser:    browser,
+		Computer:   BrowserSessionComputer{id: id, service: r.Computer},
+		Fs:         BrowserSessionFs{id: id, service: r.Fs, Watch: BrowserSessionFsWatch{id: id, service: r.Fs.Watch}},
+		Process:    BrowserSessionProcess{id: id, service: r.Process},
+		Playwright: BrowserSessionPlaywright{id: id, service: r.Playwright},
+		Replays:    BrowserSessionReplays{id: id, service: r.Replays},
+		Logs:       BrowserSessionLogs{id: id, service: r.Logs},
+	}
+}
+
+// With creates a browser session, passes it to fn and deletes it when fn returns
+// or panics, or as soon as ctx is cancelled. Deletion uses a context detached
+// from ctx, bounded by [BrowserSessionCleanupTimeout], so it also happens after
+// cancellation. The returned error joins the error of fn with that of the
+// deletion; a session that is already gone is not an error.
+func (r *BrowserService) With(ctx context.Context, body BrowserNewParams, fn func(ctx context.Context, session *BrowserSession) error, opts ...option.RequestOption) (err error) {
+	browser, err := r.New(ctx, body, opts...)
+	if err != nil {
+		return err
+	}
+	session := newSession(r, *browser)
+
+	var once sync.Once
+	var cleanupErr error
+	cleanup := func() {
+		once.Do(func() {
+			session.keepaliveDone()
+			cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), BrowserSessionCleanupTimeout)
+			defer cancel()
+			if err := r.DeleteByID(cleanupCtx, browser.SessionID, opts...); err != nil && !isNotFound(err) {
+				cleanupErr = fmt.Errorf("kernel: deleting browser session %s: %w", browser.SessionID, err)
+			}
+		})
+	}
+	stop := context.AfterFunc(ctx, cleanup)
+	defer func() {
+		stop()
+		cleanup()
+		err = errors.Join(err, cleanupErr)
+	}()
+	return fn(ctx, session)
+}
+
+func isNotFound(err error) bool {
+	var apierr *Error
+	return errors.As(err, &apierr) && apierr.StatusCode == http.StatusNotFound
+}
+
+// BrowserSessionComputer is [BrowserComputerService] bound to a session.
+type BrowserSessionComputer struct {
+	id      string
+	service BrowserComputerService
+}
+
+// Capture a screenshot of the browser instance
+func (r BrowserSessionComputer) CaptureScreenshot(ctx context.Context, body BrowserComputerCaptureScreenshotParams, opts ...option.RequestOption) (res *http.Response, err error) {
+	return r.service.CaptureScreenshot(ctx, r.id, body, opts...)
+}
+
+// Simulate a mouse click action on the browser instance
+func (r BrowserSessionComputer) ClickMouse(ctx context.Context, body BrowserComputerClickMouseParams, opts ...option.RequestOption) (err error) {
+	return r.service.ClickMouse(ctx, r.id, body, opts...)
+}
+
+// Drag the mouse along a path
+func (r BrowserSessionComputer) DragMouse(ctx context.Context, body BrowserComputerDragMouseParams, opts ...option.RequestOption) (err error) {
+	return r.service.DragMouse(ctx, r.id, body, opts...)
+}
+
+// Move the mouse cursor to the specified coordinates on the browser instance
+func (r BrowserSessionComputer) MoveMouse(ctx context.Context, body BrowserComputerMoveMouseParams, opts ...option.RequestOption) (err error) {
+	return r.service.MoveMouse(ctx, r.id, body, opts...)
+}
+
+// Press one or more keys on the host computer
+func (r BrowserSessionComputer) PressKey(ctx context.Context, body BrowserComputerPressKeyParams, opts ...option.RequestOption) (err error) {
+	return r.service.PressKey(ctx, r.id, body, opts...)
+}
+
+// Scroll the mouse wheel at a position on the host computer
+func (r BrowserSessionComputer) Scroll(ctx context.Context, body BrowserComputerScrollParams, opts ...option.RequestOption) (err error) {
+	return r.service.Scroll(ctx, r.id, body, opts...)
+}
+
+// Set cursor visibility
+func (r BrowserSessionComputer) SetCursorVisibility(ctx context.Context, body BrowserComputerSetCursorVisibilityParams, opts ...option.RequestOption) (res *BrowserComputerSetCursorVisibilityResponse, err error) {
+	return r.service.SetCursorVisibility(ctx, r.id, body, opts...)
+}
+
+// Type text on the browser instance
+func (r BrowserSessionComputer) TypeText(ctx context.Context, body BrowserComputerTypeTextParams, opts ...option.RequestOption) (err error) {
+	return r.service.TypeText(ctx, r.id, body, opts...)
+}
+
+// BrowserSessionFs is [BrowserFService] bound to a session.
+type BrowserSessionFs struct {
+	id      string
+	service BrowserFService
+	Watch   BrowserSessionFsWatch
+}
+
+// Create a new directory
+func (r BrowserSessionFs) NewDirectory(ctx context.Context, body BrowserFNewDirectoryParams, opts ...option.RequestOption) (err error) {
+	return r.service.NewDirectory(ctx, r.id, body, opts...)
+}
+
+// Delete a directory
+func (r BrowserSessionFs) DeleteDirectory(ctx context.Context, body BrowserFDeleteDirectoryParams, opts ...option.RequestOption) (err error) {
+	return r.service.DeleteDirectory(ctx, r.id, body, opts...)
+}
+
+// Delete a file
+func (r BrowserSessionFs) DeleteFile(ctx context.Context, body BrowserFDeleteFileParams, opts ...option.RequestOption) (err error) {
+	return r.service.DeleteFile(ctx, r.id, body, opts...)
+}
+
+// Returns a ZIP file containing the contents of the specified directory.
+func (r BrowserSessionFs) DownloadDirZip(ctx context.Context, query BrowserFDownloadDirZipParams, opts ...option.RequestOption) (res *http.Response, err error) {
+	return r.service.DownloadDirZip(ctx, r.id, query, opts...)
+}
+
+// Get information about a file or directory
+func (r BrowserSessionFs) FileInfo(ctx context.Context, query BrowserFFileInfoParams, opts ...option.RequestOption) (res *BrowserFFileInfoResponse, err error) {
+	return r.service.FileInfo(ctx, r.id, query, opts...)
+}
+
+// List files in a directory
+func (r BrowserSessionFs) ListFiles(ctx context.Context, query BrowserFListFilesParams, opts ...option.RequestOption) (res *[]BrowserFListFilesResponse, err error) {
+	return r.service.ListFiles(ctx, r.id, query, opts...)
+}
+
+// Move or rename a file or directory
+func (r BrowserSessionFs) Move(ctx context.Context, body BrowserFMoveParams, opts ...option.RequestOption) (err error) {
+	return r.service.Move(ctx, r.id, body, opts...)
+}
+
+// Read file contents
+func (r BrowserSessionFs) ReadFile(ctx context.Context, query BrowserFReadFileParams, opts ...option.RequestOption) (res *http.Response, err error) {
+	return r.service.ReadFile(ctx, r.id, query, opts...)
+}
+
+// Set file or directory permissions/ownership
+func (r BrowserSessionFs) SetFilePermissions(ctx context.Context, body BrowserFSetFilePermissionsParams, opts ...option.RequestOption) (err error) {
+	return r.service.SetFilePermissions(ctx, r.id, body, opts...)
+}
+
+// Allows uploading single or multiple files to the remote filesystem.
+func (r BrowserSessionFs) Upload(ctx context.Context, body BrowserFUploadParams, opts ...option.RequestOption) (err error) {
+	return r.service.Upload(ctx, r.id, body, opts...)
+}
+
+// Upload a zip file and extract its contents to the specified destination path.
+func (r BrowserSessionFs) UploadZip(ctx context.Context, body BrowserFUploadZipParams, opts ...option.RequestOption) (err error) {
+	return r.service.UploadZip(ctx, r.id, body, opts...)
+}
+
+// Write or create a file
+func (r BrowserSessionFs) WriteFile(ctx context.Context, contents io.Reader, params BrowserFWriteFileParams, opts ...option.RequestOption) (err error) {
+	return r.service.WriteFile(ctx, r.id, contents, params, opts...)
+}
+
+// BrowserSessionFsWatch is [BrowserFWatchService] bound to a session.
+type BrowserSessionFsWatch struct {
+	id      string
+	service BrowserFWatchService
+}
+
+// Watch a directory for changes
+func (r BrowserSessionFsWatch) Start(ctx context.Context, body BrowserFWatchStartParams, opts ...option.RequestOption) (res *BrowserFWatchStartResponse, err error) {
+	return r.service.Start(ctx, r.id, body, opts...)
+}
+
+// Stream filesystem events for a watch. The session ID in query is set
+// automatically.
+func (r BrowserSessionFsWatch) EventsStreaming(ctx context.Context, watchID string, query BrowserFWatchEventsParams, opts ...option.RequestOption) (stream *ssestream.Stream[BrowserFWatchEventsResponse]) {
+	query.ID = r.id
+	return r.service.EventsStreaming(ctx, watchID, query, opts...)
+}
+
+// Stop watching a directory. The session ID in body is set automatically.
+func (r BrowserSessionFsWatch) Stop(ctx context.Context, watchID string, body BrowserFWatchStopParams, opts ...option.RequestOption) (err error) {
+	body.ID = r.id
+	return r.service.Stop(ctx, watchID, body, opts...)
+}
+
+// BrowserSessionProcess is [BrowserProcessService] bound to a session. The
+// session ID in params is set automatically.
+type BrowserSessionProcess struct {
+	id      string
+	service BrowserProcessService
+}
+
+// Execute a command synchronously
+func (r BrowserSessionProcess) Exec(ctx context.Context, body BrowserProcessExecParams, opts ...option.RequestOption) (res *BrowserProcessExecResponse, err error) {
+	return r.service.Exec(ctx, r.id, body, opts...)
+}
+
+// Send signal to process
+func (r BrowserSessionProcess) Kill(ctx context.Context, processID string, params BrowserProcessKillParams, opts ...option.RequestOption) (res *BrowserProcessKillResponse, err error) {
+	params.ID = r.id
+	return r.service.Kill(ctx, processID, params, opts...)
+}
+
+// Resize a PTY-backed process terminal
+func (r BrowserSessionProcess) Resize(ctx context.Context, processID string, params BrowserProcessResizeParams, opts ...option.RequestOption) (res *BrowserProcessResizeResponse, err error) {
+	params.ID = r.id
+	return r.service.Resize(ctx, processID, params, opts...)
+}
+
+// Execute a command asynchronously
+func (r BrowserSessionProcess) Spawn(ctx context.Context, body BrowserProcessSpawnParams, opts ...option.RequestOption) (res *BrowserProcessSpawnResponse, err error) {
+	return r.service.Spawn(ctx, r.id, body, opts...)
+}
+
+// Get process status
+func (r BrowserSessionProcess) Status(ctx context.Context, processID string, query BrowserProcessStatusParams, opts ...option.RequestOption) (res *BrowserProcessStatusResponse, err error) {
+	query.ID = r.id
+	return r.service.Status(ctx, processID, query, opts...)
+}
+
+// Write to process stdin
+func (r BrowserSessionProcess) Stdin(ctx context.Context, processID string, params BrowserProcessStdinParams, opts ...option.RequestOption) (res *BrowserProcessStdinResponse, err error) {
+	params.ID = r.id
+	return r.service.Stdin(ctx, processID, params, opts...)
+}
+
+// Stream process stdout via SSE
+func (r BrowserSessionProcess) StdoutStreamStreaming(ctx context.Context, processID string, query BrowserProcessStdoutStreamParams, opts ...option.RequestOption) (stream *ssestream.Stream[BrowserProcessStdoutStreamResponse]) {
+	query.ID = r.id
+	return r.service.StdoutStreamStreaming(ctx, processID, query, opts...)
+}
+
+// BrowserSessionPlaywright is [BrowserPlaywrightService] bound to a session.
+type BrowserSessionPlaywright struct {
+	id      string
+	service BrowserPlaywrightService
+}
+
+// Execute arbitrary Playwright code in a fresh execution context against the
+// browser.
+func (r BrowserSessionPlaywright) Execute(ctx context.Context, body BrowserPlaywrightExecuteParams, opts ...option.RequestOption) (res *BrowserPlaywrightExecuteResponse, err error) {
+	return r.service.Execute(ctx, r.id, body, opts...)
+}
+
+// BrowserSessionReplays is [BrowserReplayService] bound to a session. The session
+// ID in params is set automatically.
+type BrowserSessionReplays struct {
+	id      string
+	service BrowserReplayService
+}
+
+// List all replays for the specified browser session.
+func (r BrowserSessionReplays) List(ctx context.Context, opts ...option.RequestOption) (res *[]BrowserReplayListResponse, err error) {
+	return r.service.List(ctx, r.id, opts...)
+}
+
+// Download or stream the specified replay recording.
+func (r BrowserSessionReplays) Download(ctx context.Context, replayID string, query BrowserReplayDownloadParams, opts ...option.RequestOption) (res *http.Response, err error) {
+	query.ID = r.id
+	return r.service.Download(ctx, replayID, query, opts...)
+}
+
+// Start recording the browser session and return a replay ID.
+func (r BrowserSessionReplays) Start(ctx context.Context, body BrowserReplayStartParams, opts ...option.RequestOption) (res *BrowserReplayStartResponse, err error) {
+	return r.service.Start(ctx, r.id, body, opts...)
+}
+
+// Stop the specified replay recording and persist the video.
+func (r BrowserSessionReplays) Stop(ctx context.Context, replayID string, body BrowserReplayStopParams, opts ...option.RequestOption) (err error) {
+	body.ID = r.id
+	return r.service.Stop(ctx, replayID, body, opts...)
+}
+
+// BrowserSessionLogs is [BrowserLogService] bound to a session.
+type BrowserSessionLogs struct {
+	id      string
+	service BrowserLogService
+}
+
+// Stream log files on the browser instance via SSE
+func (r BrowserSessionLogs) StreamStreaming(ctx context.Context, query BrowserLogStreamParams, opts ...option.RequestOption) (stream *ssestream.Stream[shared.LogEvent]) {
+	return r.service.StreamStreaming(ctx, r.id, query, opts...)
+}
@@ -0,0 +1,119 @@
+package kernel_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+type browserSessionServer struct {
+	mu       sync.Mutex
+	deleted  []string
+	requests []string
+	gone     bool
+}
+
+func newBrowserSessionServer(t *testing.T) (*browserSessionServer, kernel.Client) {
+	s := &browserSessionServer{}
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/browsers":
+			fmt.Fprint(w, `{"session_id":"sess_1","cdp_ws_url":"wss://cdp","created_at":"2025-01-01T00:00:00Z","headless":true,"stealth":false,"timeout_seconds":60}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/browsers/sess_1":
+			s.deleted = append(s.deleted, "sess_1")
+			if s.gone {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"code":"not_found","message":"browser not found"}`)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/browsers/sess_1/"):
+			fmt.Fprint(w, `{}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}), option.WithMaxRetries(0))
+	return s, client
+}
+
+func (s *browserSessionServer) deletions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.deleted)
+}
+
+func TestBrowserWith(t *testing.T) {
+	s, client := newBrowserSessionServer(t)
+	fnErr := errors.New("boom")
+	err := client.Browsers.With(context.Background(), kernel.BrowserNewParams{}, func(ctx context.Context, session *kernel.BrowserSession) error {
+		if session.ID() != "sess_1" || session.Browser().CdpWsURL != "wss://cdp" {
+			t.Errorf("unexpected session: %+v", session.Browser())
+		}
+		if _, err := session.Process.Kill(ctx, "proc_1", kernel.BrowserProcessKillParams{Signal: "TERM"}); err != nil {
+			t.Errorf("err should be nil: %s", err.Error())
+		}
+		if err := session.Computer.TypeText(ctx, kernel.BrowserComputerTypeTextParams{Text: "hi"}); err != nil {
+			t.Errorf("err should be nil: %s", err.Error())
+		}
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Errorf("expected the callback's error, got %v", err)
+	}
+	if s.deletions() != 1 {
+		t.Errorf("expected the session to be deleted once, got %d", s.deletions())
+	}
+	want := []string{"POST /browsers", "POST /browsers/sess_1/process/proc_1/kill", "POST /browsers/sess_1/computer/type", "DELETE /browsers/sess_1"}
+	if fmt.Sprint(s.requests) != fmt.Sprint(want) {
+		t.Errorf("requests %v, want %v", s.requests, want)
+	}
+}
+
+func TestBrowserWithPanic(t *testing.T) {
+	s, client := newBrowserSessionServer(t)
+	defer func() {
+		if recover() == nil {
+			t.Error("expected the panic to propagate")
+		}
+		if s.deletions() != 1 {
+			t.Errorf("expected the session to be deleted after a panic")
+		}
+	}()
+	client.Browsers.With(context.Background(), kernel.BrowserNewParams{}, func(ctx context.Context, session *kernel.BrowserSession) error {
+		panic("boom")
+	})
+}
+
+func TestBrowserWithCancel(t *testing.T) {
+	s, client := newBrowserSessionServer(t)
+	s.gone = true
+	ctx, cancel := context.WithCancel(context.Background())
+	err := client.Browsers.With(ctx, kernel.BrowserNewParams{}, func(ctx context.Context, session *kernel.BrowserSession) error {
+		cancel()
+		// The session is deleted as soon as the context is cancelled, without waiting
+		// for the callback to return.
+		deadline := time.Now().Add(5 * time.Second)
+		for s.deletions() == 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if s.deletions() != 1 {
+		t.Errorf("expected the session to be deleted once, got %d", s.deletions())
+	}
+}
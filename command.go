@@ -0,0 +1,373 @@
+package kernel
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kernel/kernel-go-sdk/option"
+	"github.com/kernel/kernel-go-sdk/packages/ssestream"
+)
+
+// Cmd is a command run in a browser session's environment, modeled on
+// [os/exec.Cmd]. It is built on [BrowserProcessService] and streams the
+// command's input and output instead of buffering them like [Session.Exec].
+//
+// A Cmd cannot be reused after calling its Run, Output or CombinedOutput
+// methods.
+type Cmd struct {
+	// The command to run, resolved in the browser's environment.
+	Path string
+	// The command line arguments, including the command as Args[0].
+	Args []string
+	// Environment variables in the form "key=value", added to the environment
+	// the command would otherwise get.
+	Env []string
+	// Working directory of the command. If empty, the command runs in the
+	// default directory of the browser's environment.
+	Dir string
+	// Run the command as this user, or with root privileges.
+	AsUser string
+	AsRoot bool
+	// If set, the command runs in a pseudo-terminal, which sends both its
+	// standard output and standard error to Stdout. Cols and Rows set the
+	// terminal's initial size; zero leaves it to the server.
+	Tty  bool
+	Cols int
+	Rows int
+
+	// Stdin is copied to the command's standard input as it is read. The API
+	// cannot close the command's standard input, so a command that reads until
+	// the end of its input never finishes.
+	Stdin io.Reader
+	// Stdout and Stderr receive the command's output. If nil, the output is
+	// discarded. Writes happen on a single goroutine, so they can share a
+	// writer. The output stream can only be opened once the command has been
+	// spawned, so output written right at startup, before the stream is open,
+	// may be missed.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Request options applied to every request made for the command, after the
+	// client's own.
+	Options []option.RequestOption
+
+	// Process is the running command, set by Start.
+	Process *Process
+	// ProcessState describes the exited command, set by Wait.
+	ProcessState *ProcessState
+
+	session *Session
+	ctx     context.Context
+
+	done    chan struct{}
+	waitErr error
+	waited  bool
+}
+
+// Command returns a Cmd that runs the named command with the given arguments in
+// the session.
+func (s *Session) Command(name string, arg ...string) *Cmd {
+	return s.CommandContext(context.Background(), name, arg...)
+}
+
+// CommandContext is like Command but includes a context. If the context is
+// done before the command exits, the command is killed and Wait returns the
+// context's error.
+func (s *Session) CommandContext(ctx context.Context, name string, arg ...string) *Cmd {
+	if ctx == nil {
+		panic("nil Context")
+	}
+	return &Cmd{
+		Path:    name,
+		Args:    append([]string{name}, arg...),
+		session: s,
+		ctx:     ctx,
+	}
+}
+
+// String returns the command line, for debugging.
+func (c *Cmd) String() string {
+	return strings.Join(c.Args, " ")
+}
+
+// Run starts the command and waits for it to finish. The error is an
+// [*ExitError] if the command ran and exited with a non-zero status.
+func (c *Cmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Output runs the command and returns its standard output. If Stderr is nil,
+// the standard error is captured in the returned [*ExitError].
+func (c *Cmd) Output() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("kernel: Stdout already set")
+	}
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	captureErr := c.Stderr == nil
+	if captureErr {
+		c.Stderr = &stderr
+	}
+	err := c.Run()
+	var ee *ExitError
+	if captureErr && errors.As(err, &ee) {
+		ee.Stderr = stderr.Bytes()
+	}
+	return stdout.Bytes(), err
+}
+
+// CombinedOutput runs the command and returns its standard output and standard
+// error interleaved as they were received.
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("kernel: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return nil, errors.New("kernel: Stderr already set")
+	}
+	var b bytes.Buffer
+	c.Stdout = &b
+	c.Stderr = &b
+	err := c.Run()
+	return b.Bytes(), err
+}
+
+// Start starts the command without waiting for it to finish. Call Wait to wait
+// for it and release its resources.
+func (c *Cmd) Start() error {
+	if c.Process != nil {
+		return errors.New("kernel: already started")
+	}
+	if c.session == nil {
+		return errors.New("kernel: Cmd not created by Session.Command")
+	}
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	params := BrowserProcessSpawnParams{Command: c.Path}
+	if len(c.Args) > 1 {
+		params.Args = c.Args[1:]
+	}
+	if c.Dir != "" {
+		params.Cwd = String(c.Dir)
+	}
+	if c.AsUser != "" {
+		params.AsUser = String(c.AsUser)
+	}
+	if c.AsRoot {
+		params.AsRoot = Bool(true)
+	}
+	if c.Tty {
+		params.AllocateTty = Bool(true)
+		if c.Cols > 0 && c.Rows > 0 {
+			params.Cols = Int(int64(c.Cols))
+			params.Rows = Int(int64(c.Rows))
+		}
+	}
+	if len(c.Env) > 0 {
+		params.Env = map[string]string{}
+		for _, kv := range c.Env {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				return fmt.Errorf("kernel: invalid environment variable %q", kv)
+			}
+			params.Env[k] = v
+		}
+	}
+	return c.start(params)
+}
+
+// start spawns the process and starts copying its input and output.
+func (c *Cmd) start(params BrowserProcessSpawnParams) error {
+	proc, err := c.session.Process.Spawn(c.ctx, params, c.Options...)
+	if err != nil {
+		return err
+	}
+	c.Process = &Process{ID: proc.ProcessID, Pid: int(proc.Pid), process: c.session.Process, opts: c.Options}
+	c.done = make(chan struct{})
+
+	// Stdin is copied before the output stream is open, since the stream's
+	// response may only start once the command has its input.
+	exited := make(chan struct{})
+	if c.Stdin != nil {
+		go c.copyInput(exited)
+	}
+	go func() {
+		defer close(c.done)
+		stream := c.session.Process.StdoutStreamStreaming(c.ctx, proc.ProcessID, BrowserProcessStdoutStreamParams{}, c.Options...)
+		defer stream.Close()
+		c.waitErr = c.copyOutput(stream)
+		close(exited)
+		if c.ProcessState == nil && c.ctx.Err() != nil {
+			c.Process.Kill()
+		}
+	}()
+	return nil
+}
+
+// copyOutput writes the output events of the process to Stdout and Stderr until
+// its exit event, and records its exit code.
+func (c *Cmd) copyOutput(stream *ssestream.Stream[BrowserProcessStdoutStreamResponse]) error {
+	stdout, stderr := c.Stdout, c.Stderr
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	var writeErr error
+	for stream.Next() {
+		ev := stream.Current()
+		if ev.Event == BrowserProcessStdoutStreamResponseEventExit {
+			c.ProcessState = &ProcessState{pid: c.Process.Pid, exitCode: int(ev.ExitCode)}
+			return writeErr
+		}
+		data, err := ev.Data()
+		if err != nil {
+			return fmt.Errorf("kernel: decoding output of process %s: %w", c.Process.ID, err)
+		}
+		w := stdout
+		if ev.Stream == BrowserProcessStdoutStreamResponseStreamStderr {
+			w = stderr
+		}
+		if _, err := w.Write(data); err != nil && writeErr == nil {
+			// Keep reading so the exit status is still known.
+			writeErr = err
+		}
+	}
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	// The stream can end early if the connection drops; ask for the status
+	// instead.
+	status, err := c.session.Process.Status(c.ctx, c.Process.ID, BrowserProcessStatusParams{}, c.Options...)
+	if err != nil {
+		if stream.Err() != nil {
+			return stream.Err()
+		}
+		return err
+	}
+	if status.State != BrowserProcessStatusResponseStateExited {
+		if err := stream.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("kernel: output of process %s ended before it exited", c.Process.ID)
+	}
+	c.ProcessState = &ProcessState{pid: c.Process.Pid, exitCode: int(status.ExitCode)}
+	return writeErr
+}
+
+// copyInput writes Stdin to the process until Stdin ends or the process exits.
+// Wait does not wait for it, since reading Stdin may block indefinitely.
+func (c *Cmd) copyInput(exited <-chan struct{}) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := c.Stdin.Read(buf)
+		select {
+		case <-exited:
+			return
+		default:
+		}
+		if n > 0 {
+			params := BrowserProcessStdinParams{DataB64: base64.StdEncoding.EncodeToString(buf[:n])}
+			if _, err := c.session.Process.Stdin(c.ctx, c.Process.ID, params, c.Options...); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Wait waits for the command to exit and for its output to be copied. The
+// error is an [*ExitError] if the command exited with a non-zero status.
+func (c *Cmd) Wait() error {
+	if c.Process == nil {
+		return errors.New("kernel: not started")
+	}
+	if c.waited {
+		return errors.New("kernel: Wait was already called")
+	}
+	c.waited = true
+	<-c.done
+	if c.waitErr != nil {
+		return c.waitErr
+	}
+	if !c.ProcessState.Success() {
+		return &ExitError{ProcessState: c.ProcessState}
+	}
+	return nil
+}
+
+// Process is a process started by [Cmd.Start].
+type Process struct {
+	// Server-assigned identifier of the process.
+	ID string
+	// OS process ID in the browser's environment.
+	Pid int
+
+	process BrowserSessionProcess
+	opts    []option.RequestOption
+}
+
+// Signal sends a signal to the process. The request is bounded by
+// [BrowserSessionCleanupTimeout], so it can be sent after the command's context
+// is done.
+func (p *Process) Signal(sig BrowserProcessKillParamsSignal) error {
+	ctx, cancel := context.WithTimeout(context.Background(), BrowserSessionCleanupTimeout)
+	defer cancel()
+	_, err := p.process.Kill(ctx, p.ID, BrowserProcessKillParams{Signal: sig}, p.opts...)
+	return err
+}
+
+// Kill sends KILL to the process.
+func (p *Process) Kill() error {
+	return p.Signal(BrowserProcessKillParamsSignalKill)
+}
+
+// Resize changes the terminal size of a process started with Tty set.
+func (p *Process) Resize(cols, rows int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), BrowserSessionCleanupTimeout)
+	defer cancel()
+	_, err := p.process.Resize(ctx, p.ID, BrowserProcessResizeParams{Cols: int64(cols), Rows: int64(rows)}, p.opts...)
+	return err
+}
+
+// ProcessState describes a process that has exited.
+type ProcessState struct {
+	pid      int
+	exitCode int
+}
+
+// Pid returns the OS process ID of the exited process.
+func (p *ProcessState) Pid() int { return p.pid }
+
+// ExitCode returns the exit code of the exited process.
+func (p *ProcessState) ExitCode() int { return p.exitCode }
+
+// Success reports whether the process exited with status 0.
+func (p *ProcessState) Success() bool { return p.exitCode == 0 }
+
+func (p *ProcessState) String() string {
+	return fmt.Sprintf("exit status %d", p.exitCode)
+}
+
+// ExitError reports that a command exited with a non-zero status.
+type ExitError struct {
+	*ProcessState
+	// Standard error of the command, if it was captured by [Cmd.Output].
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string {
+	return e.ProcessState.String()
+}
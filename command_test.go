@@ -0,0 +1,226 @@
+package kernel_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// processServer fakes the process endpoints of a single browser session. The
+// output stream sends stream events, then waits for the client to go away if
+// hang is set.
+type processServer struct {
+	// Output events, as "stdout:data", "stderr:data" or "exit:code".
+	stream []string
+	// If set, the stream echoes stdin to stdout before sending the stream
+	// events.
+	echo   bool
+	hang   bool
+	status string
+	// Response of the synchronous exec endpoint.
+	exec string
+
+	mu     sync.Mutex
+	spawn  map[string]any
+	spawns int
+	stdin  chan string
+	kills  []string
+	resize []string
+}
+
+func newProcessServer(t *testing.T, ps *processServer) *kernel.Session {
+	ps.stdin = make(chan string, 16)
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/browsers/sess_1":
+			fmt.Fprint(w, `{"session_id":"sess_1","cdp_ws_url":"wss://cdp","created_at":"2025-01-01T00:00:00Z","headless":true,"stealth":false,"timeout_seconds":60}`)
+		case r.URL.Path == "/browsers/sess_1/process/exec":
+			fmt.Fprint(w, ps.exec)
+		case r.URL.Path == "/browsers/sess_1/process/spawn":
+			ps.mu.Lock()
+			ps.spawn = body
+			ps.spawns++
+			ps.mu.Unlock()
+			fmt.Fprint(w, `{"process_id":"proc_1","pid":42,"started_at":"2025-01-01T00:00:00Z"}`)
+		case r.URL.Path == "/browsers/sess_1/process/proc_1/stdin":
+			data, _ := base64.StdEncoding.DecodeString(fmt.Sprint(body["data_b64"]))
+			ps.stdin <- string(data)
+			fmt.Fprintf(w, `{"written_bytes":%d}`, len(data))
+		case r.URL.Path == "/browsers/sess_1/process/proc_1/kill":
+			ps.mu.Lock()
+			ps.kills = append(ps.kills, fmt.Sprint(body["signal"]))
+			ps.mu.Unlock()
+			fmt.Fprint(w, `{"ok":true}`)
+		case r.URL.Path == "/browsers/sess_1/process/proc_1/resize":
+			ps.mu.Lock()
+			ps.resize = append(ps.resize, fmt.Sprintf("%vx%v", body["cols"], body["rows"]))
+			ps.mu.Unlock()
+			fmt.Fprint(w, `{"ok":true}`)
+		case r.URL.Path == "/browsers/sess_1/process/proc_1/status":
+			fmt.Fprint(w, ps.status)
+		case r.URL.Path == "/browsers/sess_1/process/proc_1/stdout/stream":
+			w.Header().Set("Content-Type", "text/event-stream")
+			send := func(stream, data string) {
+				if stream == "exit" {
+					fmt.Fprintf(w, "data: {\"event\":\"exit\",\"exit_code\":%s}\n\n", data)
+				} else {
+					fmt.Fprintf(w, "data: {\"stream\":%q,\"data_b64\":%q}\n\n", stream, base64.StdEncoding.EncodeToString([]byte(data)))
+				}
+				w.(http.Flusher).Flush()
+			}
+			if ps.echo {
+				send("stdout", <-ps.stdin)
+			}
+			for _, ev := range ps.stream {
+				stream, data, _ := strings.Cut(ev, ":")
+				send(stream, data)
+			}
+			if ps.hang {
+				<-r.Context().Done()
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}), option.WithMaxRetries(0))
+	session, err := client.Browsers.GetSession(context.Background(), "sess_1")
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	return session
+}
+
+func TestCmdOutput(t *testing.T) {
+	ps := &processServer{stream: []string{"stdout:hello ", "stderr:warning", "stdout:world", "exit:0"}}
+	session := newProcessServer(t, ps)
+	cmd := session.Command("echo", "hello", "world")
+	cmd.Env = []string{"LANG=C", "EMPTY="}
+	cmd.Dir = "/tmp"
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if string(out) != "hello world" {
+		t.Errorf("unexpected output %q", out)
+	}
+	spawn, _ := json.Marshal(ps.spawn)
+	if string(spawn) != `{"args":["hello","world"],"command":"echo","cwd":"/tmp","env":{"EMPTY":"","LANG":"C"}}` {
+		t.Errorf("unexpected spawn request %s", spawn)
+	}
+	if cmd.Process.Pid != 42 || cmd.ProcessState.ExitCode() != 0 || !cmd.ProcessState.Success() {
+		t.Errorf("unexpected process %+v, state %v", cmd.Process, cmd.ProcessState)
+	}
+}
+
+func TestCmdOptions(t *testing.T) {
+	ps := &processServer{stream: []string{"stdout:ok", "exit:0"}}
+	session := newProcessServer(t, ps)
+	var mu sync.Mutex
+	var paths []string
+	cmd := session.Command("true")
+	cmd.Options = []option.RequestOption{option.WithMiddleware(func(r *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		mu.Lock()
+		paths = append(paths, strings.TrimPrefix(r.URL.Path, "/browsers/sess_1/process/"))
+		mu.Unlock()
+		return next(r)
+	})}
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if fmt.Sprint(paths) != "[spawn proc_1/stdout/stream]" {
+		t.Errorf("expected the options to apply to every request, got %v", paths)
+	}
+}
+
+func TestCmdExitError(t *testing.T) {
+	ps := &processServer{stream: []string{"stdout:partial", "stderr:no such file", "exit:2"}}
+	session := newProcessServer(t, ps)
+	out, err := session.Command("cat", "/missing").Output()
+	var ee *kernel.ExitError
+	if !errors.As(err, &ee) {
+		t.Fatalf("expected an exit error, got %v", err)
+	}
+	if ee.ExitCode() != 2 || string(ee.Stderr) != "no such file" || err.Error() != "exit status 2" {
+		t.Errorf("unexpected exit error %v with stderr %q", ee, ee.Stderr)
+	}
+	if string(out) != "partial" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestCmdCombinedOutput(t *testing.T) {
+	ps := &processServer{stream: []string{"stdout:a", "stderr:b", "stdout:c", "exit:0"}}
+	session := newProcessServer(t, ps)
+	out, err := session.Command("sh", "-c", "true").CombinedOutput()
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if string(out) != "abc" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestCmdStdin(t *testing.T) {
+	ps := &processServer{echo: true, stream: []string{"exit:0"}, hang: true}
+	session := newProcessServer(t, ps)
+	cmd := session.Command("cat")
+	cmd.Stdin = strings.NewReader("typed input")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if string(out) != "typed input" {
+		t.Errorf("expected stdin to be echoed, got %q", out)
+	}
+}
+
+func TestCmdStreamEndsEarly(t *testing.T) {
+	ps := &processServer{stream: []string{"stdout:some"}, status: `{"state":"exited","exit_code":1}`}
+	session := newProcessServer(t, ps)
+	err := session.Command("false").Run()
+	var ee *kernel.ExitError
+	if !errors.As(err, &ee) || ee.ExitCode() != 1 {
+		t.Errorf("expected the exit code from the status, got %v", err)
+	}
+
+	ps.status = `{"state":"running"}`
+	if err := session.Command("sleep", "60").Run(); err == nil || !strings.Contains(err.Error(), "ended before it exited") {
+		t.Errorf("expected the lost stream to be reported, got %v", err)
+	}
+}
+
+func TestCmdContextKills(t *testing.T) {
+	ps := &processServer{stream: []string{"stdout:started"}, hang: true}
+	session := newProcessServer(t, ps)
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := session.CommandContext(ctx, "sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if err := cmd.Start(); err == nil {
+		t.Error("expected a second Start to fail")
+	}
+	cancel()
+	if err := cmd.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the context error, got %v", err)
+	}
+	if err := cmd.Process.Signal(kernel.BrowserProcessKillParamsSignalTerm); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if fmt.Sprint(ps.kills) != "[KILL TERM]" {
+		t.Errorf("expected the process to be killed, got %v", ps.kills)
+	}
+}
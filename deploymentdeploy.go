@@ -0,0 +1,196 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/option"
+	"github.com/kernel/kernel-go-sdk/packages/param"
+	"github.com/kernel/kernel-go-sdk/shared"
+)
+
+// DeploymentDeployResponse is the outcome of a deployment that reached the
+// "running" state.
+type DeploymentDeployResponse struct {
+	// Final state of the deployment.
+	Deployment DeploymentStateEventDeployment
+	// The app version the deployment created, with its actions.
+	App DeploymentFollowResponseAppVersionSummaryEvent
+	// Build logs emitted while the deployment was followed.
+	Logs []shared.LogEvent
+}
+
+// DeploymentFailedError is returned by [DeploymentService.Deploy] and
+// [DeploymentService.Wait] when a deployment fails or is stopped before it is
+// running, or when its event stream reports an error.
+type DeploymentFailedError struct {
+	DeploymentID string
+	// Status of the deployment, "failed" or "stopped". Empty if the failure was only
+	// reported as an error event.
+	Status string
+	// Status reason reported by the deployment.
+	StatusReason string
+	// Error reported on the event stream, if any.
+	Event *shared.ErrorModel
+	// Build logs emitted before the failure.
+	Logs []shared.LogEvent
+}
+
+func (e *DeploymentFailedError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "kernel: deployment %s", e.DeploymentID)
+	if e.Status != "" {
+		fmt.Fprintf(&sb, " %s", e.Status)
+	} else {
+		sb.WriteString(" failed")
+	}
+	if e.StatusReason != "" {
+		fmt.Fprintf(&sb, ": %s", e.StatusReason)
+	}
+	if e.Event != nil {
+		fmt.Fprintf(&sb, ": %s: %s", e.Event.Code, e.Event.Message)
+	}
+	return sb.String()
+}
+
+// Deploy creates a deployment and follows it until it is running, returning the
+// app version it created along with the build logs. If the deployment fails or
+// is stopped, the error is a [*DeploymentFailedError] carrying the status reason.
+func (r *DeploymentService) Deploy(ctx context.Context, body DeploymentNewParams, opts ...option.RequestOption) (res *DeploymentDeployResponse, err error) {
+	dep, err := r.New(ctx, body, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return r.Wait(ctx, dep.ID, DeploymentWaitParams{Version: body.Version}, opts...)
+}
+
+type DeploymentWaitParams struct {
+	// Name of the application being deployed, if known.
+	AppName param.Opt[string]
+	// Version label of the application being deployed, if known.
+	Version param.Opt[string]
+}
+
+// Wait follows an existing deployment until it is running or has failed. See
+// [DeploymentService.Deploy]. If the event stream ends without reporting the app
+// version, it is looked up among the apps filtered by params.AppName and
+// params.Version; without them, every app is searched.
+func (r *DeploymentService) Wait(ctx context.Context, id string, params DeploymentWaitParams, opts ...option.RequestOption) (res *DeploymentDeployResponse, err error) {
+	if id == "" {
+		return nil, errors.New("missing required id parameter")
+	}
+	res = &DeploymentDeployResponse{}
+	var haveSummary bool
+	for {
+		// Following again replays the logs from the start, so the ones already
+		// collected are skipped while the replay matches them.
+		replayed := 0
+		stream := r.FollowStreaming(ctx, id, DeploymentFollowParams{}, opts...)
+		for stream.Next() {
+			event := stream.Current()
+			switch event.Event {
+			case "log":
+				log := event.AsLog()
+				if replayed < len(res.Logs) && res.Logs[replayed].Message == log.Message && res.Logs[replayed].Timestamp.Equal(log.Timestamp) {
+					replayed++
+					break
+				}
+				res.Logs = append(res.Logs, log)
+				replayed = len(res.Logs)
+			case "deployment_state":
+				res.Deployment = event.AsDeploymentState().Deployment
+			case "app_version_summary":
+				res.App = event.AsDeploymentFollowResponseAppVersionSummaryEvent()
+				haveSummary = true
+			case "error":
+				stream.Close()
+				model := event.AsErrorEvent().Error
+				return nil, &DeploymentFailedError{DeploymentID: id, Event: &model, Logs: res.Logs}
+			}
+			if err := deploymentFailure(id, res); err != nil {
+				stream.Close()
+				return nil, err
+			}
+			if res.Deployment.Status == string(DeploymentGetResponseStatusRunning) && haveSummary {
+				stream.Close()
+				return res, nil
+			}
+		}
+		stream.Close()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// The stream ended without both the running state and the app summary, so fall
+		// back to the deployment and app records.
+		dep, err := r.Get(ctx, id, opts...)
+		if err != nil {
+			return nil, err
+		}
+		res.Deployment = DeploymentStateEventDeployment{
+			ID:                dep.ID,
+			CreatedAt:         dep.CreatedAt,
+			Region:            dep.Region,
+			Status:            string(dep.Status),
+			EntrypointRelPath: dep.EntrypointRelPath,
+			EnvVars:           dep.EnvVars,
+			StatusReason:      dep.StatusReason,
+			UpdatedAt:         dep.UpdatedAt,
+		}
+		if err := deploymentFailure(id, res); err != nil {
+			return nil, err
+		}
+		if res.Deployment.Status == string(DeploymentGetResponseStatusRunning) {
+			if !haveSummary {
+				filter := AppListParams{AppName: params.AppName, Version: params.Version}
+				if res.App, err = r.appVersionSummary(ctx, id, filter, opts); err != nil {
+					return nil, err
+				}
+			}
+			return res, nil
+		}
+		if err := sleepContext(ctx, time.Second); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func deploymentFailure(id string, res *DeploymentDeployResponse) error {
+	switch res.Deployment.Status {
+	case string(DeploymentGetResponseStatusFailed), string(DeploymentGetResponseStatusStopped):
+		return &DeploymentFailedError{
+			DeploymentID: id,
+			Status:       res.Deployment.Status,
+			StatusReason: res.Deployment.StatusReason,
+			Logs:         res.Logs,
+		}
+	}
+	return nil
+}
+
+// appVersionSummary finds the app version created by a deployment.
+func (r *DeploymentService) appVersionSummary(ctx context.Context, id string, filter AppListParams, opts []option.RequestOption) (DeploymentFollowResponseAppVersionSummaryEvent, error) {
+	apps := NewAppService(r.Options...)
+	iter := apps.ListAutoPaging(ctx, filter, opts...)
+	for iter.Next() {
+		app := iter.Current()
+		if app.Deployment == id {
+			return DeploymentFollowResponseAppVersionSummaryEvent{
+				ID:        app.ID,
+				Actions:   app.Actions,
+				AppName:   app.AppName,
+				Region:    app.Region,
+				Timestamp: time.Now(),
+				Version:   app.Version,
+				EnvVars:   app.EnvVars,
+			}, nil
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return DeploymentFollowResponseAppVersionSummaryEvent{}, err
+	}
+	return DeploymentFollowResponseAppVersionSummaryEvent{}, fmt.Errorf("kernel: no app version found for deployment %s", id)
+}
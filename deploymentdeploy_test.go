@@ -0,0 +1,155 @@
+package kernel_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+func newDeploymentServer(t *testing.T, events ...string) kernel.Client {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/deployments":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"dep_1","created_at":"2025-01-01T00:00:00Z","region":"aws.us-east-1a","status":"queued"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/deployments/dep_1/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, e := range events {
+				fmt.Fprintf(w, "data: %s\n\n", e)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return client
+}
+
+func deploymentState(status, reason string) string {
+	return fmt.Sprintf(`{"event":"deployment_state","timestamp":"2025-01-01T00:00:00Z","deployment":{"id":"dep_1","created_at":"2025-01-01T00:00:00Z","region":"aws.us-east-1a","status":%q,"status_reason":%q}}`, status, reason)
+}
+
+func TestDeploymentDeploy(t *testing.T) {
+	client := newDeploymentServer(t,
+		deploymentState("in_progress", ""),
+		`{"event":"log","timestamp":"2025-01-01T00:00:00Z","message":"installing"}`,
+		`{"event":"app_version_summary","timestamp":"2025-01-01T00:00:00Z","id":"ver_1","app_name":"my-app","version":"1","region":"aws.us-east-1a","actions":[{"name":"scrape"}]}`,
+		deploymentState("running", ""),
+	)
+	res, err := client.Deployments.Deploy(context.Background(), kernel.DeploymentNewParams{
+		EntrypointRelPath: kernel.String("index.ts"),
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.Deployment.Status != "running" || res.App.AppName != "my-app" || len(res.App.Actions) != 1 || res.App.Actions[0].Name != "scrape" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if len(res.Logs) != 1 || res.Logs[0].Message != "installing" {
+		t.Errorf("expected build logs to be collected, got %+v", res.Logs)
+	}
+}
+
+func TestDeploymentWaitRefollowDoesNotDuplicateLogs(t *testing.T) {
+	logs := []string{
+		`{"event":"log","timestamp":"2025-01-01T00:00:00Z","message":"installing"}`,
+		`{"event":"log","timestamp":"2025-01-01T00:00:01Z","message":"building"}`,
+	}
+	follows := [][]string{
+		// The first stream ends before the deployment is running.
+		{deploymentState("in_progress", ""), logs[0]},
+		{logs[0], logs[1], `{"event":"app_version_summary","timestamp":"2025-01-01T00:00:00Z","id":"ver_1","app_name":"my-app","version":"1","region":"aws.us-east-1a","actions":[]}`, deploymentState("running", "")},
+	}
+	var calls int
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/deployments/dep_1":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"dep_1","created_at":"2025-01-01T00:00:00Z","region":"aws.us-east-1a","status":"in_progress"}`)
+		case "/deployments/dep_1/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, e := range follows[min(calls, len(follows)-1)] {
+				fmt.Fprintf(w, "data: %s\n\n", e)
+			}
+			calls++
+		default:
+			http.NotFound(w, r)
+		}
+	}), option.WithMaxRetries(0))
+
+	res, err := client.Deployments.Wait(context.Background(), "dep_1", kernel.DeploymentWaitParams{})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if calls != 2 || len(res.Logs) != 2 || res.Logs[0].Message != "installing" || res.Logs[1].Message != "building" {
+		t.Errorf("expected each log once after %d follows, got %+v", calls, res.Logs)
+	}
+}
+
+func TestDeploymentWaitLooksUpAppByNameAndVersion(t *testing.T) {
+	var query string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/deployments/dep_1":
+			fmt.Fprint(w, `{"id":"dep_1","created_at":"2025-01-01T00:00:00Z","region":"aws.us-east-1a","status":"running"}`)
+		case "/deployments/dep_1/events":
+			// The stream ends without an app version summary.
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: %s\n\n", deploymentState("running", ""))
+		case "/apps":
+			query = r.URL.RawQuery
+			w.Header().Set("X-Next-Offset", "0")
+			fmt.Fprint(w, `[{"id":"ver_1","app_name":"my-app","version":"2","deployment":"dep_1","region":"aws.us-east-1a","actions":[{"name":"scrape"}],"env_vars":{}}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}), option.WithMaxRetries(0))
+
+	res, err := client.Deployments.Wait(context.Background(), "dep_1", kernel.DeploymentWaitParams{
+		AppName: kernel.String("my-app"),
+		Version: kernel.String("2"),
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.App.ID != "ver_1" || res.App.Version != "2" {
+		t.Errorf("unexpected app version: %+v", res.App)
+	}
+	if query != "app_name=my-app&version=2" {
+		t.Errorf("expected the apps to be filtered by name and version, got %q", query)
+	}
+}
+
+func TestDeploymentDeployFailed(t *testing.T) {
+	client := newDeploymentServer(t,
+		`{"event":"log","timestamp":"2025-01-01T00:00:00Z","message":"npm ERR!"}`,
+		deploymentState("failed", "build failed"),
+	)
+	_, err := client.Deployments.Deploy(context.Background(), kernel.DeploymentNewParams{})
+	var failed *kernel.DeploymentFailedError
+	if !errors.As(err, &failed) {
+		t.Fatalf("expected a DeploymentFailedError, got %v", err)
+	}
+	if failed.StatusReason != "build failed" || len(failed.Logs) != 1 {
+		t.Errorf("unexpected error: %+v", failed)
+	}
+	if failed.Error() != "kernel: deployment dep_1 failed: build failed" {
+		t.Errorf("unexpected message: %s", failed.Error())
+	}
+}
+
+func TestDeploymentDeployErrorEvent(t *testing.T) {
+	client := newDeploymentServer(t,
+		`{"event":"error","timestamp":"2025-01-01T00:00:00Z","error":{"code":"internal","message":"builder crashed"}}`,
+	)
+	_, err := client.Deployments.Deploy(context.Background(), kernel.DeploymentNewParams{})
+	var failed *kernel.DeploymentFailedError
+	if !errors.As(err, &failed) || failed.Event == nil || failed.Event.Code != "internal" {
+		t.Fatalf("expected a DeploymentFailedError with the error event, got %v", err)
+	}
+}
@@ -0,0 +1,400 @@
+package kernel
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kernel/kernel-go-sdk/internal/apiform"
+	"github.com/kernel/kernel-go-sdk/internal/gitignore"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// DeploymentContentHashEnvVar is the environment variable [DeploymentService.NewFromDir]
+// adds to each deployment's env vars to record the content hash of the uploaded
+// code, unless [DeploymentNewFromDirParams.OmitContentHash] is set. The API has
+// no other place to keep it: apps report their env vars back, which is how
+// identical code is detected on later deploys. The variable is visible to the
+// app's code like any other, and the SDK's env var diffs ignore it.
+const DeploymentContentHashEnvVar = "KERNEL_CONTENT_HASH"
+
+type DeploymentNewFromDirParams struct {
+	// Settings for the deployment. EntrypointRelPath is required and is relative to
+	// the directory; File and Source must be left unset.
+	Deployment DeploymentNewParams
+	// Name of the application the directory deploys. Together with
+	// Deployment.Version it is used to look up an already deployed version with the
+	// same content hash, in which case the deployment is skipped unless
+	// Deployment.Force is set. Without both AppName and Deployment.Version, the
+	// code is always deployed.
+	AppName string
+	// If true, [DeploymentContentHashEnvVar] is not added to the deployment's env
+	// vars and no existing version is looked up, so the code is always deployed.
+	OmitContentHash bool
+	// Additional ignore patterns in .gitignore format, applied after the ignore files
+	// at the root of the directory.
+	Ignore []string
+	// Called as files are archived and the archive is uploaded. Calls are serialized.
+	OnProgress func(DeploymentUploadProgress)
+}
+
+// DeploymentUploadProgress reports how far [DeploymentService.NewFromDir] has
+// come. File counts and byte totals refer to the uncompressed files selected for
+// the archive.
+type DeploymentUploadProgress struct {
+	FilesArchived int
+	TotalFiles    int
+	BytesArchived int64
+	TotalBytes    int64
+	// Bytes of the request body sent so far, including multipart framing.
+	BytesUploaded int64
+}
+
+type DeploymentNewFromDirResponse struct {
+	// The created deployment, or nil if the deployment was skipped.
+	Deployment *DeploymentNewResponse
+	// True if a deployed version with the same content hash already existed and
+	// Deployment.Force was not set.
+	Skipped bool
+	// The deployed app version matching the content hash, when Skipped is true.
+	ExistingApp *AppListResponse
+	// Hex-encoded SHA-256 over the archived files, the entrypoint and the
+	// environment variables.
+	ContentHash string
+	// Number of files in the archive.
+	Files int
+}
+
+// NewFromDir deploys the contents of a local directory. The archive is built
+// while it is uploaded rather than in memory. Files matched by .gitignore or
+// .kernelignore files in the directory (or its subdirectories) are left out, as
+// is the .git directory. Symlinks are kept as symlinks if they resolve inside the
+// directory; a symlink that escapes it is an error.
+//
+// The content hash is recorded in the deployment's env vars as
+// [DeploymentContentHashEnvVar]; see [DeploymentNewFromDirParams] for when a
+// deployment is skipped.
+func (r *DeploymentService) NewFromDir(ctx context.Context, dir string, params DeploymentNewFromDirParams, opts ...option.RequestOption) (res *DeploymentNewFromDirResponse, err error) {
+	body := params.Deployment
+	if body.File != nil || body.Source.URL != "" {
+		return nil, errors.New("kernel: File and Source must not be set when deploying from a directory")
+	}
+	if !body.EntrypointRelPath.Valid() || body.EntrypointRelPath.Value == "" {
+		return nil, errors.New("missing required entrypoint_rel_path parameter")
+	}
+	if _, ok := body.EnvVars[DeploymentContentHashEnvVar]; ok && !params.OmitContentHash {
+		return nil, fmt.Errorf("kernel: env var %s is set by NewFromDir; set OmitContentHash to use your own", DeploymentContentHashEnvVar)
+	}
+	entrypoint := path.Clean(filepath.ToSlash(body.EntrypointRelPath.Value))
+
+	files, err := collectDeploymentFiles(dir, params.Ignore)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	var totalBytes int64
+	for _, f := range files {
+		found = found || (f.rel == entrypoint && f.link == "")
+		totalBytes += f.size
+	}
+	if !found {
+		return nil, fmt.Errorf("kernel: entrypoint %q is not a file in the deployed directory", entrypoint)
+	}
+
+	hash, err := hashDeploymentFiles(files, entrypoint, body.EnvVars)
+	if err != nil {
+		return nil, err
+	}
+	res = &DeploymentNewFromDirResponse{ContentHash: hash, Files: len(files)}
+
+	if !params.OmitContentHash && !body.Force.Or(false) && params.AppName != "" && body.Version.Valid() {
+		apps := NewAppService(r.Options...)
+		iter := apps.ListAutoPaging(ctx, AppListParams{
+			AppName: String(params.AppName),
+			Version: body.Version,
+		}, opts...)
+		for iter.Next() {
+			app := iter.Current()
+			if app.EnvVars[DeploymentContentHashEnvVar] == hash {
+				res.Skipped = true
+				res.ExistingApp = &app
+				return res, nil
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !params.OmitContentHash {
+		envVars := make(map[string]string, len(body.EnvVars)+1)
+		for k, v := range body.EnvVars {
+			envVars[k] = v
+		}
+		envVars[DeploymentContentHashEnvVar] = hash
+		body.EnvVars = envVars
+	}
+	body.EntrypointRelPath = String(entrypoint)
+
+	progress := &deploymentProgress{fn: params.OnProgress}
+	progress.state.TotalFiles = len(files)
+	progress.state.TotalBytes = totalBytes
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeDeploymentMultipart(mw, body, dir, files, progress))
+	}()
+	defer pr.Close()
+
+	upload := &progressReader{r: pr, progress: progress}
+	res.Deployment, err = r.New(ctx, DeploymentNewParams{}, slices.Concat(opts, []option.RequestOption{option.WithRequestBody(mw.FormDataContentType(), upload)})...)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+type deploymentFile struct {
+	// rel is the slash-separated path relative to the deployed directory.
+	rel  string
+	abs  string
+	mode fs.FileMode
+	size int64
+	// link is the target of a symlink, relative to the link's directory, or "" for
+	// regular files.
+	link string
+}
+
+var deploymentIgnoreFiles = []string{".gitignore", ".kernelignore"}
+
+func collectDeploymentFiles(dir string, extra []string) ([]deploymentFile, error) {
+	realRoot, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	if realRoot, err = filepath.Abs(realRoot); err != nil {
+		return nil, err
+	}
+
+	var matcher gitignore.Matcher
+	var files []deploymentFile
+	err = filepath.WalkDir(realRoot, func(abs string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(realRoot, abs)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel != "." && (d.Name() == ".git" || matcher.Match(rel, true)) {
+				return filepath.SkipDir
+			}
+			base := rel
+			if base == "." {
+				base = ""
+			}
+			for _, name := range deploymentIgnoreFiles {
+				if err := addIgnoreFile(&matcher, base, filepath.Join(abs, name)); err != nil {
+					return err
+				}
+			}
+			if rel == "." {
+				for _, p := range extra {
+					matcher.AddPattern("", p)
+				}
+			}
+			return nil
+		}
+		if matcher.Match(rel, false) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.Mode().IsRegular():
+			files = append(files, deploymentFile{rel: rel, abs: abs, mode: info.Mode(), size: info.Size()})
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := deploymentSymlink(realRoot, abs)
+			if err != nil {
+				return fmt.Errorf("kernel: symlink %s: %w", rel, err)
+			}
+			files = append(files, deploymentFile{rel: rel, abs: abs, mode: info.Mode(), link: link})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func addIgnoreFile(m *gitignore.Matcher, base string, name string) error {
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.Add(base, f)
+}
+
+// deploymentSymlink returns the target of the symlink at abs, relative to the
+// link's own directory, after checking that it resolves inside realRoot.
+func deploymentSymlink(realRoot string, abs string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(realRoot, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.New("target is outside the deployed directory")
+	}
+	realDir, err := filepath.EvalSymlinks(filepath.Dir(abs))
+	if err != nil {
+		return "", err
+	}
+	target, err := filepath.Rel(realDir, resolved)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(target), nil
+}
+
+func hashDeploymentFiles(files []deploymentFile, entrypoint string, envVars map[string]string) (string, error) {
+	h := sha256.New()
+	writeField := func(s string) {
+		io.WriteString(h, strconv.Itoa(len(s)))
+		io.WriteString(h, ":")
+		io.WriteString(h, s)
+	}
+	writeField("entrypoint")
+	writeField(entrypoint)
+
+	keys := make([]string, 0, len(envVars))
+	for k := range envVars {
+		if k != DeploymentContentHashEnvVar {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeField("env")
+		writeField(k)
+		writeField(envVars[k])
+	}
+
+	for _, f := range files {
+		if f.link != "" {
+			writeField("link")
+			writeField(f.rel)
+			writeField(f.link)
+			continue
+		}
+		writeField("file")
+		writeField(f.rel)
+		writeField(strconv.FormatInt(f.size, 10))
+		if err := copyFile(h, f.abs); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func copyFile(w io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// writeDeploymentMultipart writes the deployment form, streaming the zipped
+// files into the file part.
+func writeDeploymentMultipart(mw *multipart.Writer, body DeploymentNewParams, dir string, files []deploymentFile, progress *deploymentProgress) error {
+	if err := apiform.MarshalRoot(body, mw); err != nil {
+		return err
+	}
+	if err := apiform.WriteExtras(mw, body.ExtraFields()); err != nil {
+		return err
+	}
+	part, err := mw.CreateFormFile("file", filepath.Base(filepath.Clean(dir))+".zip")
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(part)
+	for _, f := range files {
+		header := &zip.FileHeader{Name: f.rel, Method: zip.Deflate}
+		header.SetMode(f.mode)
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if f.link != "" {
+			if _, err := io.WriteString(w, f.link); err != nil {
+				return err
+			}
+		} else if err := copyFile(w, f.abs); err != nil {
+			return err
+		}
+		progress.update(func(p *DeploymentUploadProgress) {
+			p.FilesArchived++
+			p.BytesArchived += f.size
+		})
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return mw.Close()
+}
+
+type deploymentProgress struct {
+	mu    sync.Mutex
+	state DeploymentUploadProgress
+	fn    func(DeploymentUploadProgress)
+}
+
+func (p *deploymentProgress) update(fn func(*DeploymentUploadProgress)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fn(&p.state)
+	if p.fn != nil {
+		p.fn(p.state)
+	}
+}
+
+type progressReader struct {
+	r        io.Reader
+	progress *deploymentProgress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if n > 0 {
+		r.progress.update(func(p *DeploymentUploadProgress) { p.BytesUploaded += int64(n) })
+	}
+	return n, err
+}
@@ -0,0 +1,176 @@
+package kernel_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDeploymentNewFromDir(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"index.ts":           "export default {}",
+		"lib/util.ts":        "export const x = 1",
+		"lib/util.test.ts":   "test",
+		"debug.log":          "noise",
+		"node_modules/a/x":   "dep",
+		".gitignore":         "*.log\nnode_modules/\n",
+		".kernelignore":      "*.test.ts\n",
+		".git/HEAD":          "ref",
+		"assets/.gitignore":  "!keep.log\n",
+		"assets/keep.log":    "kept",
+		"assets/nested/a.md": "doc",
+	})
+	if err := os.Symlink("lib/util.ts", filepath.Join(dir, "util.ts")); err != nil {
+		t.Fatal(err)
+	}
+
+	var archived []string
+	var form map[string]string
+	var hashes []string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/apps":
+			w.Header().Set("X-Next-Offset", "0")
+			items := []map[string]any{}
+			for _, h := range hashes {
+				items = append(items, map[string]any{"id": "app_1", "app_name": "my-app", "version": "1", "deployment": "dep_1", "region": "aws.us-east-1a", "actions": []any{}, "env_vars": map[string]string{kernel.DeploymentContentHashEnvVar: h}})
+			}
+			json.NewEncoder(w).Encode(items)
+		case r.Method == http.MethodPost && r.URL.Path == "/deployments":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("invalid multipart body: %s", err)
+			}
+			form = map[string]string{}
+			for k, v := range r.MultipartForm.Value {
+				form[k] = v[0]
+			}
+			f, _, err := r.FormFile("file")
+			if err != nil {
+				t.Fatalf("missing file part: %s", err)
+			}
+			data, _ := io.ReadAll(f)
+			zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("invalid zip: %s", err)
+			}
+			archived = nil
+			for _, zf := range zr.File {
+				archived = append(archived, zf.Name)
+			}
+			sort.Strings(archived)
+			fmt.Fprint(w, `{"id":"dep_1","created_at":"2025-01-01T00:00:00Z","region":"aws.us-east-1a","status":"queued"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	var last kernel.DeploymentUploadProgress
+	params := kernel.DeploymentNewFromDirParams{
+		AppName: "my-app",
+		Deployment: kernel.DeploymentNewParams{
+			EntrypointRelPath: kernel.String("index.ts"),
+			Version:           kernel.String("1"),
+			EnvVars:           map[string]string{"MODE": "prod"},
+		},
+		Ignore:     []string{"assets/nested/"},
+		OnProgress: func(p kernel.DeploymentUploadProgress) { last = p },
+	}
+	res, err := client.Deployments.NewFromDir(context.Background(), dir, params)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.Skipped || res.Deployment == nil || res.Deployment.ID != "dep_1" {
+		t.Fatalf("expected a new deployment, got %+v", res)
+	}
+
+	want := []string{".gitignore", ".kernelignore", "assets/.gitignore", "assets/keep.log", "index.ts", "lib/util.ts", "util.ts"}
+	if !reflect.DeepEqual(archived, want) {
+		t.Errorf("archived %v, want %v", archived, want)
+	}
+	if form["entrypoint_rel_path"] != "index.ts" || form["version"] != "1" {
+		t.Errorf("unexpected form fields: %v", form)
+	}
+	if !strings.Contains(fmt.Sprint(form), res.ContentHash) {
+		t.Errorf("expected the content hash to be sent as an env var, got %v", form)
+	}
+	if last.FilesArchived != len(want) || last.BytesUploaded == 0 {
+		t.Errorf("unexpected final progress: %+v", last)
+	}
+
+	hashes = append(hashes, res.ContentHash)
+	res, err = client.Deployments.NewFromDir(context.Background(), dir, params)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if !res.Skipped || res.ExistingApp == nil {
+		t.Errorf("expected identical code to be skipped, got %+v", res)
+	}
+
+	params.Deployment.Force = kernel.Bool(true)
+	res, err = client.Deployments.NewFromDir(context.Background(), dir, params)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.Skipped {
+		t.Errorf("expected Force to deploy identical code")
+	}
+
+	params.Deployment.Force = kernel.Bool(false)
+	params.OmitContentHash = true
+	res, err = client.Deployments.NewFromDir(context.Background(), dir, params)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.Skipped || strings.Contains(fmt.Sprint(form), res.ContentHash) {
+		t.Errorf("expected OmitContentHash to deploy without the hash env var, got %v", form)
+	}
+
+	params.OmitContentHash = false
+	params.Deployment.EnvVars = map[string]string{kernel.DeploymentContentHashEnvVar: "mine"}
+	if _, err := client.Deployments.NewFromDir(context.Background(), dir, params); err == nil {
+		t.Errorf("expected a user-set %s to be rejected", kernel.DeploymentContentHashEnvVar)
+	}
+}
+
+func TestDeploymentNewFromDirRejectsEscapingSymlink(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"index.ts": ""})
+	if err := os.Symlink(os.TempDir(), filepath.Join(dir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	client := kernel.NewClient(option.WithBaseURL("http://127.0.0.1:0"), option.WithAPIKey("My API Key"))
+	_, err := client.Deployments.NewFromDir(context.Background(), dir, kernel.DeploymentNewFromDirParams{
+		Deployment: kernel.DeploymentNewParams{EntrypointRelPath: kernel.String("index.ts")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "outside the deployed directory") {
+		t.Errorf("expected an escaping symlink to be rejected, got %v", err)
+	}
+}
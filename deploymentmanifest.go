@@ -0,0 +1,325 @@
+package kernel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kernel/kernel-go-sdk/internal/miniyaml"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// DeploymentManifest declares how an app is deployed. It is usually loaded from
+// a kernel.yaml (or JSON) file with [LoadDeploymentManifest]:
+//
+//	name: my-app
+//	version: "1.2.0"
+//	entrypoint: index.ts
+//	region: aws.us-east-1a
+//	env_files: [.env]
+//	env:
+//	  LOG_LEVEL: info
+//	  API_KEY: ${API_KEY}
+//	dir: .
+//
+// Code comes either from a local directory or from a GitHub repository:
+//
+//	source:
+//	  url: https://github.com/acme/apps
+//	  ref: main
+//	  path: scraper
+//	  token: ${GITHUB_TOKEN}
+//
+// References of the form ${NAME} in env values and in the source's url, ref and
+// token are resolved from the process environment when the manifest is turned
+// into deployment params; "$$" stands for a literal "$". Secrets therefore never
+// need to be written into the manifest itself. The token is marked with
+// [MarkSensitive], and env values resolved from references are marked as by
+// [MarkSensitiveEnvVars], so short ones such as a port are left unmarked.
+type DeploymentManifest struct {
+	// Name of the application. Used to detect already deployed code; the deployed
+	// code itself defines the app name.
+	Name string `json:"name"`
+	// Version label for the application.
+	Version string `json:"version,omitempty"`
+	// Relative path to the entrypoint, within Dir or the source path.
+	Entrypoint string `json:"entrypoint"`
+	// Region for deployment, see [DeploymentNewParamsRegion].
+	Region string `json:"region,omitempty"`
+	// Allow overwriting an existing app version.
+	Force bool `json:"force,omitempty"`
+	// Environment variables for the app. Values may reference the process
+	// environment as ${NAME}.
+	Env map[string]string `json:"env,omitempty"`
+	// Dotenv files, relative to the manifest, loaded in order before Env, which takes
+	// precedence. Their values are marked sensitive as by [MarkSensitiveEnvVars]
+	// when the manifest is resolved.
+	EnvFiles []string `json:"env_files,omitempty"`
+	// Local directory to deploy, relative to the manifest. Defaults to the directory
+	// containing the manifest when Source is not set.
+	Dir string `json:"dir,omitempty"`
+	// GitHub repository to deploy instead of a local directory.
+	Source *DeploymentManifestSource `json:"source,omitempty"`
+
+	// baseDir is the directory relative paths are resolved against.
+	baseDir string
+}
+
+type DeploymentManifestSource struct {
+	// Base repository URL (without blob/tree suffixes).
+	URL string `json:"url"`
+	// Git ref (branch, tag, or commit SHA) to fetch.
+	Ref string `json:"ref"`
+	// Path within the repo to deploy (omit to use repo root).
+	Path string `json:"path,omitempty"`
+	// Token for private repositories, normally a ${NAME} reference to the process
+	// environment.
+	Token string `json:"token,omitempty"`
+}
+
+// LoadDeploymentManifest reads and validates a manifest. Files ending in .json
+// are parsed as JSON and anything else as YAML. Relative paths in the manifest
+// are resolved against the manifest's directory.
+func LoadDeploymentManifest(path string) (*DeploymentManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &DeploymentManifest{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(m)
+	} else {
+		err = unmarshalStrictYAML(data, m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kernel: parsing manifest %s: %w", path, err)
+	}
+	m.baseDir = filepath.Dir(path)
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("kernel: manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+func unmarshalStrictYAML(data []byte, v any) error {
+	value, err := miniyaml.Parse(data)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate checks the manifest without resolving references or reading files.
+func (m *DeploymentManifest) Validate() error {
+	var errs []error
+	if m.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if m.Entrypoint == "" {
+		errs = append(errs, errors.New("entrypoint is required"))
+	} else if filepath.IsAbs(m.Entrypoint) {
+		errs = append(errs, errors.New("entrypoint must be a relative path"))
+	}
+	if m.Region != "" && m.Region != string(DeploymentNewParamsRegionAwsUsEast1a) {
+		errs = append(errs, fmt.Errorf("unsupported region %q", m.Region))
+	}
+	for k := range m.Env {
+		if !envVarName.MatchString(k) {
+			errs = append(errs, fmt.Errorf("invalid env var name %q", k))
+		}
+	}
+	if m.Source != nil {
+		if m.Dir != "" {
+			errs = append(errs, errors.New("dir and source are mutually exclusive"))
+		}
+		if m.Source.URL == "" {
+			errs = append(errs, errors.New("source.url is required"))
+		}
+		if m.Source.Ref == "" {
+			errs = append(errs, errors.New("source.ref is required"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewParams resolves the manifest's env files and references from the process
+// environment and returns the params for [DeploymentService.New]. Values from
+// env files and values resolved from references are marked sensitive as by
+// [MarkSensitiveEnvVars], so they are redacted from debug logs and errors. For
+// manifests
+// that deploy a local directory, File is left unset; see
+// [DeploymentService.Apply].
+func (m *DeploymentManifest) NewParams() (DeploymentNewParams, error) {
+	if err := m.Validate(); err != nil {
+		return DeploymentNewParams{}, err
+	}
+	env := map[string]string{}
+	for _, name := range m.EnvFiles {
+		vars, err := LoadEnvFile(m.resolvePath(name))
+		if err != nil {
+			return DeploymentNewParams{}, err
+		}
+		MarkSensitiveEnvVars(vars)
+		for k, v := range vars {
+			env[k] = v
+		}
+	}
+	referenced := map[string]string{}
+	for k, v := range m.Env {
+		resolved, err := expandEnvReferences(v)
+		if err != nil {
+			return DeploymentNewParams{}, fmt.Errorf("env %s: %w", k, err)
+		}
+		if resolved != v {
+			referenced[k] = resolved
+		}
+		env[k] = resolved
+	}
+	MarkSensitiveEnvVars(referenced)
+
+	params := DeploymentNewParams{}
+	if len(env) > 0 {
+		params.EnvVars = env
+	}
+	if m.Version != "" {
+		params.Version = String(m.Version)
+	}
+	if m.Force {
+		params.Force = Bool(true)
+	}
+	if m.Region != "" {
+		params.Region = DeploymentNewParamsRegion(m.Region)
+	}
+	if m.Source != nil {
+		var source DeploymentNewParamsSource
+		var err error
+		if source.URL, err = expandEnvReferences(m.Source.URL); err != nil {
+			return DeploymentNewParams{}, fmt.Errorf("source.url: %w", err)
+		}
+		if source.Ref, err = expandEnvReferences(m.Source.Ref); err != nil {
+			return DeploymentNewParams{}, fmt.Errorf("source.ref: %w", err)
+		}
+		source.Type = "github"
+		source.Entrypoint = filepath.ToSlash(m.Entrypoint)
+		if m.Source.Path != "" {
+			source.Path = String(m.Source.Path)
+		}
+		if m.Source.Token != "" {
+			token, err := expandEnvReferences(m.Source.Token)
+			if err != nil {
+				return DeploymentNewParams{}, fmt.Errorf("source.token: %w", err)
+			}
+			MarkSensitive(token)
+			source.Auth = DeploymentNewParamsSourceAuth{Token: token, Method: "github_token"}
+		}
+		params.Source = source
+	} else {
+		params.EntrypointRelPath = String(filepath.ToSlash(m.Entrypoint))
+	}
+	return params, nil
+}
+
+func (m *DeploymentManifest) resolvePath(name string) string {
+	if filepath.IsAbs(name) || m.baseDir == "" {
+		return name
+	}
+	return filepath.Join(m.baseDir, name)
+}
+
+// DeploymentApplyResponse is the outcome of [DeploymentService.Apply].
+type DeploymentApplyResponse struct {
+	// The running deployment and the app version it created. Nil if the deployment
+	// was skipped.
+	Deployment *DeploymentDeployResponse
+	// True if the manifest deploys a local directory whose code, entrypoint and env
+	// vars are identical to the deployed version, and force is not set.
+	Skipped bool
+	// Content hash of the deployed directory, for manifests that deploy one.
+	ContentHash string
+}
+
+// Apply loads the manifest at manifestPath, resolves its env files and
+// references, and deploys it, waiting until the deployment is running. Local
+// directories are deployed with [DeploymentService.NewFromDir], so unchanged code
+// is skipped; GitHub sources are always deployed. A failed deployment is reported
+// as a [*DeploymentFailedError].
+func (r *DeploymentService) Apply(ctx context.Context, manifestPath string, opts ...option.RequestOption) (res *DeploymentApplyResponse, err error) {
+	m, err := LoadDeploymentManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	params, err := m.NewParams()
+	if err != nil {
+		return nil, fmt.Errorf("kernel: manifest %s: %w", manifestPath, err)
+	}
+
+	res = &DeploymentApplyResponse{}
+	if m.Source != nil {
+		res.Deployment, err = r.Deploy(ctx, params, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	dir := m.Dir
+	if dir == "" {
+		dir = "."
+	}
+	created, err := r.NewFromDir(ctx, m.resolvePath(dir), DeploymentNewFromDirParams{
+		Deployment: params,
+		AppName:    m.Name,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res.ContentHash = created.ContentHash
+	if created.Skipped {
+		res.Skipped = true
+		return res, nil
+	}
+	res.Deployment, err = r.Wait(ctx, created.Deployment.ID, DeploymentWaitParams{AppName: String(m.Name), Version: params.Version}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+var envReference = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnvReferences replaces ${NAME} with the value of NAME in the process
+// environment. Unset variables are an error rather than an empty string.
+func expandEnvReferences(s string) (string, error) {
+	var missing []string
+	out := envReference.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+		name := ref[2 : len(ref)-1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
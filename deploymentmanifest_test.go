@@ -0,0 +1,124 @@
+package kernel_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/internal/redact"
+)
+
+func TestLoadDeploymentManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"kernel.yaml": `
+name: my-app
+version: "1.10"
+entrypoint: index.ts
+region: aws.us-east-1a
+env_files: [.env]
+env:
+  API_KEY: ${MANIFEST_TEST_API_KEY}
+  PORT: ${MANIFEST_TEST_PORT}
+  PRICE: $$5
+`,
+		".env": "# defaults\nLOG_LEVEL=debug\nDB_PASSWORD=manifest-test-pw\nexport API_KEY='overridden'\n",
+	})
+	t.Setenv("MANIFEST_TEST_API_KEY", "manifest-test-key")
+	t.Setenv("MANIFEST_TEST_PORT", "8080")
+
+	m, err := kernel.LoadDeploymentManifest(filepath.Join(dir, "kernel.yaml"))
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	params, err := m.NewParams()
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if params.Version.Value != "1.10" || params.EntrypointRelPath.Value != "index.ts" || params.Region != kernel.DeploymentNewParamsRegionAwsUsEast1a {
+		t.Errorf("unexpected params: %+v", params)
+	}
+	want := map[string]string{"API_KEY": "manifest-test-key", "PORT": "8080", "LOG_LEVEL": "debug", "DB_PASSWORD": "manifest-test-pw", "PRICE": "$5"}
+	for k, v := range want {
+		if params.EnvVars[k] != v {
+			t.Errorf("env %s = %q, want %q", k, params.EnvVars[k], v)
+		}
+	}
+	if !redact.Contains("manifest-test-pw") || redact.Contains("debug") {
+		t.Errorf("expected the long env file value, and only it, to be marked sensitive")
+	}
+	if !redact.Contains("manifest-test-key") || redact.Contains("8080") {
+		t.Errorf("expected the long referenced value, and only it, to be marked sensitive")
+	}
+
+	os.Unsetenv("MANIFEST_TEST_API_KEY")
+	if _, err := m.NewParams(); err == nil || !strings.Contains(err.Error(), "MANIFEST_TEST_API_KEY is not set") {
+		t.Errorf("expected an unset reference to be an error, got %v", err)
+	}
+}
+
+func TestLoadDeploymentManifestSource(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"kernel.json": `{"name":"my-app","entrypoint":"index.ts","source":{"url":"https://github.com/acme/apps","ref":"main","path":"scraper","token":"${MANIFEST_TEST_TOKEN}"}}`,
+	})
+	t.Setenv("MANIFEST_TEST_TOKEN", "ghp_x")
+
+	m, err := kernel.LoadDeploymentManifest(filepath.Join(dir, "kernel.json"))
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	params, err := m.NewParams()
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	src := params.Source
+	if src.Type != "github" || src.URL != "https://github.com/acme/apps" || src.Entrypoint != "index.ts" || src.Path.Value != "scraper" || src.Auth.Token != "ghp_x" {
+		t.Errorf("unexpected source: %+v", src)
+	}
+	if params.EntrypointRelPath.Valid() {
+		t.Errorf("expected entrypoint_rel_path to be unset for a source deployment")
+	}
+}
+
+func TestLoadDeploymentManifestInvalid(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"kernel.yaml": "name: my-app\nregion: eu-west-1\ndir: .\nsource:\n  url: https://github.com/acme/apps\n",
+		"typo.yaml":   "name: my-app\nentrypoint: index.ts\nentry_point: main.ts\n",
+	})
+	_, err := kernel.LoadDeploymentManifest(filepath.Join(dir, "kernel.yaml"))
+	if err == nil {
+		t.Fatal("expected an invalid manifest to be rejected")
+	}
+	for _, msg := range []string{"entrypoint is required", "unsupported region", "mutually exclusive", "source.ref is required"} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected %q in %q", msg, err.Error())
+		}
+	}
+	if _, err := kernel.LoadDeploymentManifest(filepath.Join(dir, "typo.yaml")); err == nil || !strings.Contains(err.Error(), "entry_point") {
+		t.Errorf("expected unknown fields to be rejected, got %v", err)
+	}
+}
+
+func TestDeploymentApply(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"kernel.yaml":  "name: my-app\nentrypoint: index.ts\ndir: app\n",
+		"app/index.ts": "export default {}",
+	})
+	client := newDeploymentServer(t,
+		`{"event":"app_version_summary","timestamp":"2025-01-01T00:00:00Z","id":"ver_1","app_name":"my-app","version":"1","region":"aws.us-east-1a","actions":[]}`,
+		deploymentState("running", ""),
+	)
+	res, err := client.Deployments.Apply(context.Background(), filepath.Join(dir, "kernel.yaml"))
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.Skipped || res.ContentHash == "" || res.Deployment == nil || res.Deployment.App.AppName != "my-app" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+}
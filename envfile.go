@@ -0,0 +1,306 @@
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kernel/kernel-go-sdk/internal/redact"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// LoadEnvFile reads a dotenv file into a map suitable for
+// [DeploymentNewParams.EnvVars]. References are resolved from the process
+// environment; see [ParseEnvFile] for the syntax.
+func LoadEnvFile(name string) (map[string]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	vars, err := ParseEnvFile(f, os.LookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return vars, nil
+}
+
+// ParseEnvFile parses dotenv content. Each entry has the form KEY=VALUE, with an
+// optional "export " prefix; blank lines and lines starting with # are ignored.
+//
+//   - Unquoted values end at the end of the line or at " #", and are trimmed.
+//   - 'Single-quoted' values are taken literally and may span several lines.
+//   - "Double-quoted" values may span several lines and understand the escapes
+//     \n, \r, \t, \", \\ and \$.
+//
+// In unquoted and double-quoted values, ${NAME} is replaced by a variable defined
+// earlier in the file or, failing that, by lookup. A reference that cannot be
+// resolved is an error rather than an empty string.
+func ParseEnvFile(r io.Reader, lookup func(name string) (string, bool)) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &envParser{src: strings.ReplaceAll(string(data), "\r\n", "\n"), line: 1, vars: map[string]string{}, lookup: lookup}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.vars, nil
+}
+
+type envParser struct {
+	src    string
+	pos    int
+	line   int
+	vars   map[string]string
+	lookup func(string) (string, bool)
+}
+
+func (p *envParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *envParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *envParser) skipSpaces() {
+	for !p.eof() && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipLine consumes the rest of the current line, which may only hold a comment.
+func (p *envParser) skipLine(allowText bool) error {
+	p.skipSpaces()
+	if !p.eof() && p.src[p.pos] != '\n' && p.src[p.pos] != '#' && !allowText {
+		return p.errorf("unexpected text after value")
+	}
+	for !p.eof() && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+	if !p.eof() {
+		p.pos++
+		p.line++
+	}
+	return nil
+}
+
+func (p *envParser) parse() error {
+	for !p.eof() {
+		p.skipSpaces()
+		if p.eof() {
+			break
+		}
+		if c := p.src[p.pos]; c == '\n' || c == '#' {
+			p.skipLine(true)
+			continue
+		}
+		if strings.HasPrefix(p.src[p.pos:], "export ") {
+			p.pos += len("export ")
+			p.skipSpaces()
+		}
+		start := p.pos
+		for !p.eof() && isEnvNameByte(p.src[p.pos], p.pos == start) {
+			p.pos++
+		}
+		key := p.src[start:p.pos]
+		p.skipSpaces()
+		if key == "" || p.eof() || p.src[p.pos] != '=' {
+			return p.errorf("expected KEY=VALUE")
+		}
+		p.pos++
+		p.skipSpaces()
+		value, err := p.value()
+		if err != nil {
+			return err
+		}
+		p.vars[key] = value
+	}
+	return nil
+}
+
+func isEnvNameByte(c byte, first bool) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || !first && c >= '0' && c <= '9'
+}
+
+func (p *envParser) value() (string, error) {
+	if p.eof() {
+		return "", nil
+	}
+	switch p.src[p.pos] {
+	case '\'':
+		end := strings.IndexByte(p.src[p.pos+1:], '\'')
+		if end < 0 {
+			return "", p.errorf("unterminated single-quoted value")
+		}
+		value := p.src[p.pos+1 : p.pos+1+end]
+		p.line += strings.Count(value, "\n")
+		p.pos += end + 2
+		return value, p.skipLine(false)
+	case '"':
+		startLine := p.line
+		var sb strings.Builder
+		p.pos++
+		for {
+			if p.eof() {
+				p.line = startLine
+				return "", p.errorf("unterminated double-quoted value")
+			}
+			c := p.src[p.pos]
+			switch {
+			case c == '"':
+				p.pos++
+				return sb.String(), p.skipLine(false)
+			case c == '\\' && p.pos+1 < len(p.src):
+				p.pos++
+				switch e := p.src[p.pos]; e {
+				case 'n':
+					sb.WriteByte('\n')
+				case 'r':
+					sb.WriteByte('\r')
+				case 't':
+					sb.WriteByte('\t')
+				case '"', '\\', '$':
+					sb.WriteByte(e)
+				default:
+					sb.WriteByte('\\')
+					sb.WriteByte(e)
+				}
+				p.pos++
+			case c == '$' && strings.HasPrefix(p.src[p.pos:], "${"):
+				n, v, err := p.reference(p.src[p.pos:])
+				if err != nil {
+					return "", err
+				}
+				sb.WriteString(v)
+				p.pos += n
+			default:
+				if c == '\n' {
+					p.line++
+				}
+				sb.WriteByte(c)
+				p.pos++
+			}
+		}
+	default:
+		end := p.pos
+		for end < len(p.src) && p.src[end] != '\n' && !(p.src[end] == '#' && (p.src[end-1] == ' ' || p.src[end-1] == '\t')) {
+			end++
+		}
+		raw := strings.TrimSpace(p.src[p.pos:end])
+		var sb strings.Builder
+		for i := 0; i < len(raw); {
+			if strings.HasPrefix(raw[i:], "${") {
+				n, v, err := p.reference(raw[i:])
+				if err != nil {
+					return "", err
+				}
+				sb.WriteString(v)
+				i += n
+				continue
+			}
+			sb.WriteByte(raw[i])
+			i++
+		}
+		p.pos = end
+		return sb.String(), p.skipLine(true)
+	}
+}
+
+// reference resolves the ${NAME} reference at the start of s, returning its
+// length and value.
+func (p *envParser) reference(s string) (int, string, error) {
+	end := strings.IndexByte(s, '}')
+	if end < 0 || strings.Contains(s[:end], "\n") {
+		return 0, "", p.errorf("unterminated variable reference")
+	}
+	name := s[2:end]
+	if !envVarName.MatchString(name) {
+		return 0, "", p.errorf("invalid variable reference %q", s[:end+1])
+	}
+	v, ok := p.vars[name]
+	if !ok && p.lookup != nil {
+		v, ok = p.lookup(name)
+	}
+	if !ok {
+		return 0, "", p.errorf("environment variable %s is not set", name)
+	}
+	return end + 1, v, nil
+}
+
+// MarkSensitive registers values, such as secrets passed in env vars, that must
+// not appear in logs. For the rest of the process's life they are redacted from
+// the output of [option.WithRedactedDebugLog], and from [Error] messages and
+// response dumps of clients made with [option.WithRedactedErrors]:
+//
+//	client := kernel.NewClient(option.WithRedactedErrors())
+func MarkSensitive(values ...string) {
+	redact.Add(values...)
+}
+
+// SensitiveEnvValueMinLength is the length below which [MarkSensitiveEnvVars]
+// leaves values unmarked unless they are named. Short values such as "1",
+// "true" or "prod" are rarely secrets, and redacting them everywhere would
+// mangle unrelated log output.
+const SensitiveEnvValueMinLength = 8
+
+// MarkSensitiveEnvVars marks the values of the named variables in env as
+// sensitive. If no names are given, it marks every value at least
+// [SensitiveEnvValueMinLength] bytes long. See [MarkSensitive].
+func MarkSensitiveEnvVars(env map[string]string, names ...string) {
+	if len(names) == 0 {
+		for _, v := range env {
+			if len(v) >= SensitiveEnvValueMinLength {
+				redact.Add(v)
+			}
+		}
+		return
+	}
+	for _, name := range names {
+		redact.Add(env[name])
+	}
+}
+
+// String describes the change, replacing values marked with [MarkSensitive].
+func (c EnvVarChange) String() string {
+	from, to := redact.String(c.From), redact.String(c.To)
+	switch c.Kind {
+	case "added":
+		return fmt.Sprintf("+ %s=%s", c.Name, to)
+	case "removed":
+		return fmt.Sprintf("- %s=%s", c.Name, from)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Name, from, to)
+	}
+}
+
+// DiffEnvVars compares the env vars of a deployed app version with a local set,
+// such as one read with [LoadEnvFile]. Changes are described from deployed to
+// local, so "added" means the variable is only set locally. The content hash set
+// by [DeploymentService.NewFromDir] is ignored.
+func DiffEnvVars(deployed, local map[string]string) []EnvVarChange {
+	var changes []EnvVarChange
+	for _, c := range diffEnvVars(deployed, local) {
+		if c.Name != DeploymentContentHashEnvVar {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// EnvDrift compares the env vars of a deployed app version with local, returning
+// no changes if they match. See [DiffEnvVars].
+func (r *AppService) EnvDrift(ctx context.Context, appName string, version string, local map[string]string, opts ...option.RequestOption) (res []EnvVarChange, err error) {
+	if appName == "" || version == "" {
+		return nil, fmt.Errorf("missing required appName or version parameter")
+	}
+	page, err := r.List(ctx, AppListParams{AppName: String(appName), Version: String(version), Limit: Int(1)}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Items) == 0 {
+		return nil, fmt.Errorf("kernel: app %s has no version %s", appName, version)
+	}
+	return DiffEnvVars(page.Items[0].EnvVars, local), nil
+}
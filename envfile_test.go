@@ -0,0 +1,146 @@
+package kernel_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/internal/redact"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	src := `
+# comment
+export HOST=example.com
+PORT = 8080 # trailing comment
+URL=https://${HOST}:${PORT}/path#frag
+LITERAL='${HOST} stays \n'
+QUOTED="line1\nline2 \"q\" \${HOST} ${HOST}"
+MULTI="first
+second"
+KEY='-----BEGIN KEY-----
+abc
+-----END KEY-----'
+EMPTY=
+FROM_ENV=${PARENT}
+`
+	lookup := func(name string) (string, bool) {
+		if name == "PARENT" {
+			return "inherited", true
+		}
+		return "", false
+	}
+	got, err := kernel.ParseEnvFile(strings.NewReader(src), lookup)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	want := map[string]string{
+		"HOST":     "example.com",
+		"PORT":     "8080",
+		"URL":      "https://example.com:8080/path#frag",
+		"LITERAL":  `${HOST} stays \n`,
+		"QUOTED":   "line1\nline2 \"q\" ${HOST} example.com",
+		"MULTI":    "first\nsecond",
+		"KEY":      "-----BEGIN KEY-----\nabc\n-----END KEY-----",
+		"EMPTY":    "",
+		"FROM_ENV": "inherited",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+
+	for src, msg := range map[string]string{
+		"A=1\nB=${MISSING}\n":  "line 2: environment variable MISSING is not set",
+		"A=1\nB=\"open\n\n":    "line 2: unterminated double-quoted value",
+		"A='x' trailing\n":     "line 1: unexpected text after value",
+		"not a line\n":         "line 1: expected KEY=VALUE",
+		"A=1\n\nB=\"x\" y\n":   "line 3: unexpected text after value",
+		"A=\"a\nb\"\nC=${D}\n": "line 3: environment variable D is not set",
+	} {
+		if _, err := kernel.ParseEnvFile(strings.NewReader(src), lookup); err == nil || err.Error() != msg {
+			t.Errorf("ParseEnvFile(%q) error = %v, want %q", src, err, msg)
+		}
+	}
+}
+
+func TestMarkSensitive(t *testing.T) {
+	const secret = "sk-test-redaction-0a1b2c"
+	kernel.MarkSensitiveEnvVars(map[string]string{"API_KEY": secret, "MODE": "prod"}, "API_KEY")
+
+	var logs bytes.Buffer
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"code":"bad_request","message":"invalid value %s"}`, secret)
+	}), option.WithMaxRetries(0), option.WithRedactedErrors(), option.WithRedactedDebugLog(log.New(&logs, "", 0)))
+
+	_, err := client.Invocations.New(context.Background(), kernel.InvocationNewParams{
+		ActionName: "run",
+		AppName:    "my-app",
+		Version:    "1",
+		Payload:    kernel.String(secret),
+	})
+	var apierr *kernel.Error
+	if !errors.As(err, &apierr) {
+		t.Fatalf("expected an API error, got %v", err)
+	}
+	dumps := map[string]string{
+		"error":    apierr.Error(),
+		"response": string(apierr.DumpResponse(true)),
+		"log":      logs.String(),
+	}
+	for name, dump := range dumps {
+		if strings.Contains(dump, secret) || !strings.Contains(dump, "[REDACTED]") {
+			t.Errorf("expected the secret to be redacted from the %s, got:\n%s", name, dump)
+		}
+	}
+	if !strings.Contains(logs.String(), "my-app") {
+		t.Errorf("expected unmarked values to be logged, got:\n%s", logs.String())
+	}
+
+	change := kernel.DiffEnvVars(map[string]string{"API_KEY": "old"}, map[string]string{"API_KEY": secret})
+	if len(change) != 1 || change[0].String() != "~ API_KEY: old -> [REDACTED]" {
+		t.Errorf("unexpected drift: %v", change)
+	}
+}
+
+func TestMarkSensitiveEnvVarsSkipsShortValues(t *testing.T) {
+	kernel.MarkSensitiveEnvVars(map[string]string{"TOKEN": "tok-0123456789", "DEBUG": "1", "STAGE": "prod"})
+	if !redact.Contains("tok-0123456789") {
+		t.Errorf("expected the long value to be marked sensitive")
+	}
+	if redact.Contains("1") || redact.Contains("prod") {
+		t.Errorf("expected short values not to be marked sensitive")
+	}
+}
+
+func TestAppEnvDrift(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Next-Offset", "0")
+		if r.URL.Query().Get("version") != "1" {
+			t.Errorf("expected the version to be filtered, got %s", r.URL.RawQuery)
+		}
+		fmt.Fprintf(w, `[{"id":"a1","app_name":"my-app","version":"1","deployment":"dep_1","region":"aws.us-east-1a","actions":[],"env_vars":{"MODE":"prod","OLD":"x",%q:"hash"}}]`, kernel.DeploymentContentHashEnvVar)
+	}))
+
+	drift, err := client.Apps.EnvDrift(context.Background(), "my-app", "1", map[string]string{"MODE": "prod", "NEW": "y"})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	want := []kernel.EnvVarChange{
+		{Name: "NEW", To: "y", Kind: "added"},
+		{Name: "OLD", From: "x", Kind: "removed"},
+	}
+	if !reflect.DeepEqual(drift, want) {
+		t.Errorf("drift %+v, want %+v", drift, want)
+	}
+}
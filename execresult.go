@@ -0,0 +1,205 @@
+package kernel
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/kernel/kernel-go-sdk/option"
+	"github.com/kernel/kernel-go-sdk/packages/ssestream"
+)
+
+// ExecErrorOutputLimit is the number of bytes of each output an [ExecError]
+// keeps. Longer output is truncated to its last ExecErrorOutputLimit bytes,
+// where errors are usually reported.
+const ExecErrorOutputLimit = 4096
+
+// Stdout returns the decoded standard output of the command.
+func (r BrowserProcessExecResponse) Stdout() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(r.StdoutB64)
+}
+
+// Stderr returns the decoded standard error of the command.
+func (r BrowserProcessExecResponse) Stderr() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(r.StderrB64)
+}
+
+// Err returns an [*ExecError] if the command exited with a non-zero status, and
+// nil otherwise.
+func (r BrowserProcessExecResponse) Err() error {
+	if r.ExitCode == 0 {
+		return nil
+	}
+	e := &ExecError{ExitCode: int(r.ExitCode)}
+	stdout, _ := r.Stdout()
+	stderr, _ := r.Stderr()
+	e.Stdout, e.Truncated = truncateOutput(stdout)
+	var truncated bool
+	e.Stderr, truncated = truncateOutput(stderr)
+	e.Truncated = e.Truncated || truncated
+	return e
+}
+
+// Data returns the decoded data of an output event.
+func (r BrowserProcessStdoutStreamResponse) Data() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(r.DataB64)
+}
+
+// ExecError reports that a command run by [Session.ExecOutput] or checked with
+// [BrowserProcessExecResponse.Err] exited with a non-zero status.
+type ExecError struct {
+	// The command line, if known.
+	Command  string
+	ExitCode int
+	// The command's output, truncated to [ExecErrorOutputLimit] bytes each.
+	Stdout []byte
+	Stderr []byte
+	// Whether Stdout or Stderr was truncated.
+	Truncated bool
+}
+
+func (e *ExecError) Error() string {
+	msg := fmt.Sprintf("exit status %d", e.ExitCode)
+	if e.Command != "" {
+		msg = fmt.Sprintf("command %q: %s", e.Command, msg)
+	}
+	if stderr := strings.TrimSpace(string(e.Stderr)); stderr != "" {
+		msg += ": " + stderr
+	}
+	return "kernel: " + msg
+}
+
+func truncateOutput(b []byte) ([]byte, bool) {
+	if len(b) <= ExecErrorOutputLimit {
+		return b, false
+	}
+	return b[len(b)-ExecErrorOutputLimit:], true
+}
+
+// ExecOutput runs a command in the browser's environment, waits for it to
+// finish and returns its decoded standard output. If the command exits with a
+// non-zero status, the error is an [*ExecError].
+func (s *Session) ExecOutput(ctx context.Context, command string, args []string, opts ...option.RequestOption) ([]byte, error) {
+	res, err := s.Exec(ctx, command, args, opts...)
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := res.Stdout()
+	if err != nil {
+		return nil, fmt.Errorf("kernel: decoding output of %s: %w", command, err)
+	}
+	if err := res.Err(); err != nil {
+		err.(*ExecError).Command = strings.Join(append([]string{command}, args...), " ")
+		return stdout, err
+	}
+	return stdout, nil
+}
+
+// ProcessLine is a line of output read by a [ProcessLineReader].
+type ProcessLine struct {
+	Stream BrowserProcessStdoutStreamResponseStream
+	// The line without its line ending.
+	Text string
+}
+
+// ProcessLineReader splits the output stream of a process into lines. Standard
+// output and standard error are split separately, so interleaved chunks of the
+// two do not mix, and lines or UTF-8 sequences split across chunks are joined
+// back together.
+//
+//	lines := kernel.NewProcessLineReader(session.Process.StdoutStreamStreaming(ctx, id, kernel.BrowserProcessStdoutStreamParams{}))
+//	for lines.Next() {
+//		fmt.Println(lines.Current().Text)
+//	}
+//	if err := lines.Err(); err != nil {
+//		...
+//	}
+type ProcessLineReader struct {
+	stream  *ssestream.Stream[BrowserProcessStdoutStreamResponse]
+	partial map[BrowserProcessStdoutStreamResponseStream]*bytes.Buffer
+	lines   []ProcessLine
+	cur     ProcessLine
+	exited  bool
+	code    int
+	err     error
+	done    bool
+}
+
+// NewProcessLineReader returns a reader of the lines of stream. The reader
+// closes the stream once it is exhausted.
+func NewProcessLineReader(stream *ssestream.Stream[BrowserProcessStdoutStreamResponse]) *ProcessLineReader {
+	return &ProcessLineReader{stream: stream, partial: map[BrowserProcessStdoutStreamResponseStream]*bytes.Buffer{}}
+}
+
+// Next advances to the next line, and reports whether there is one. A final
+// line without a line ending is returned when the stream ends.
+func (r *ProcessLineReader) Next() bool {
+	for len(r.lines) == 0 {
+		if r.done {
+			return false
+		}
+		r.fill()
+	}
+	r.cur, r.lines = r.lines[0], r.lines[1:]
+	return true
+}
+
+// fill reads the next event of the stream into lines.
+func (r *ProcessLineReader) fill() {
+	if !r.stream.Next() {
+		r.err = r.stream.Err()
+		r.finish()
+		return
+	}
+	ev := r.stream.Current()
+	if ev.Event == BrowserProcessStdoutStreamResponseEventExit {
+		r.exited, r.code = true, int(ev.ExitCode)
+		r.finish()
+		return
+	}
+	data, err := ev.Data()
+	if err != nil {
+		r.err = fmt.Errorf("kernel: decoding process output: %w", err)
+		r.finish()
+		return
+	}
+	buf := r.partial[ev.Stream]
+	if buf == nil {
+		buf = &bytes.Buffer{}
+		r.partial[ev.Stream] = buf
+	}
+	buf.Write(data)
+	for {
+		i := bytes.IndexByte(buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := buf.Next(i + 1)
+		r.lines = append(r.lines, ProcessLine{Stream: ev.Stream, Text: string(bytes.TrimSuffix(line[:i], []byte("\r")))})
+	}
+}
+
+// finish flushes the unterminated lines, standard output first, and closes the
+// stream.
+func (r *ProcessLineReader) finish() {
+	r.done = true
+	for _, s := range []BrowserProcessStdoutStreamResponseStream{BrowserProcessStdoutStreamResponseStreamStdout, BrowserProcessStdoutStreamResponseStreamStderr} {
+		if buf := r.partial[s]; buf != nil && buf.Len() > 0 {
+			r.lines = append(r.lines, ProcessLine{Stream: s, Text: buf.String()})
+			buf.Reset()
+		}
+	}
+	r.stream.Close()
+}
+
+// Current returns the line Next advanced to.
+func (r *ProcessLineReader) Current() ProcessLine { return r.cur }
+
+// Err returns the error that ended the stream, if any.
+func (r *ProcessLineReader) Err() error { return r.err }
+
+// ExitCode returns the exit code of the process once its exit event has been
+// read.
+func (r *ProcessLineReader) ExitCode() (code int, exited bool) { return r.code, r.exited }
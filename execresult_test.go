@@ -0,0 +1,68 @@
+package kernel_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk"
+)
+
+func TestSessionExecOutput(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString
+	ps := &processServer{exec: fmt.Sprintf(`{"exit_code":0,"stdout_b64":%q,"stderr_b64":""}`, b64([]byte("ok\n")))}
+	session := newProcessServer(t, ps)
+	out, err := session.ExecOutput(context.Background(), "echo", []string{"ok"})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if string(out) != "ok\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	stderr := strings.Repeat("x", kernel.ExecErrorOutputLimit) + "no such file\n"
+	ps.exec = fmt.Sprintf(`{"exit_code":1,"stdout_b64":%q,"stderr_b64":%q}`, b64([]byte("partial")), b64([]byte(stderr)))
+	out, err = session.ExecOutput(context.Background(), "ls", []string{"/missing"})
+	var ee *kernel.ExecError
+	if !errors.As(err, &ee) {
+		t.Fatalf("expected an exec error, got %v", err)
+	}
+	if ee.ExitCode != 1 || ee.Command != "ls /missing" || !ee.Truncated || len(ee.Stderr) != kernel.ExecErrorOutputLimit || string(ee.Stdout) != "partial" {
+		t.Errorf("unexpected exec error %+v", ee)
+	}
+	if !strings.HasSuffix(err.Error(), "no such file") || string(out) != "partial" {
+		t.Errorf("unexpected error %q with output %q", err.Error(), out)
+	}
+}
+
+func TestProcessLineReader(t *testing.T) {
+	ps := &processServer{stream: []string{
+		"stdout:first li",
+		"stderr:warn",
+		"stdout:ne\r\nh\xc3",
+		"stdout:\xa9llo\n",
+		"stderr:ing\nunterminated",
+		"stdout:last",
+		"exit:3",
+	}}
+	session := newProcessServer(t, ps)
+	stream := session.Process.StdoutStreamStreaming(context.Background(), "proc_1", kernel.BrowserProcessStdoutStreamParams{})
+	lines := kernel.NewProcessLineReader(stream)
+	var got []string
+	for lines.Next() {
+		got = append(got, fmt.Sprintf("%s:%s", lines.Current().Stream, lines.Current().Text))
+	}
+	if err := lines.Err(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	want := "[stdout:first line stdout:héllo stderr:warning stdout:last stderr:unterminated]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if code, exited := lines.ExitCode(); !exited || code != 3 {
+		t.Errorf("expected exit code 3, got %d (exited %v)", code, exited)
+	}
+}
@@ -0,0 +1,141 @@
+// Package gitignore matches slash-separated paths against patterns written in
+// the .gitignore format.
+package gitignore
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Matcher holds patterns from any number of ignore files. Later patterns take
+// precedence over earlier ones, as in git.
+type Matcher struct {
+	patterns []pattern
+}
+
+type pattern struct {
+	// base is the directory of the ignore file the pattern came from, relative to
+	// the root, or "" for the root itself.
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// basename patterns contain no slash and match the last path element at any
+	// depth below base.
+	basename bool
+}
+
+// Add parses patterns in .gitignore format from r. base is the slash-separated
+// directory containing the ignore file, relative to the root; patterns only apply
+// to paths below it.
+func (m *Matcher) Add(base string, r io.Reader) error {
+	base = strings.Trim(base, "/")
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m.AddPattern(base, scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// AddPattern adds a single pattern. Blank lines and comments are ignored.
+func (m *Matcher) AddPattern(base string, line string) {
+	line = strings.TrimRight(strings.TrimSuffix(line, "\r"), " ")
+	if strings.HasSuffix(line, "\\") {
+		// A trailing backslash escaped a space that was trimmed above.
+		line += " "
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+	p := pattern{base: strings.Trim(base, "/")}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return
+	}
+	p.basename = !strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	re, err := regexp.Compile("^" + translate(line) + "$")
+	if err != nil {
+		return
+	}
+	p.re = re
+	m.patterns = append(m.patterns, p)
+}
+
+// Match reports whether the slash-separated path, relative to the root, is
+// ignored. Paths inside an ignored directory are not reported as ignored
+// themselves; callers walking a tree should skip ignored directories.
+func (m *Matcher) Match(path string, isDir bool) bool {
+	path = strings.Trim(path, "/")
+	ignored := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		rel := path
+		if p.base != "" {
+			if !strings.HasPrefix(path, p.base+"/") {
+				continue
+			}
+			rel = path[len(p.base)+1:]
+		}
+		if p.basename {
+			rel = rel[strings.LastIndex(rel, "/")+1:]
+		}
+		if p.re.MatchString(rel) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// translate converts a glob to a regular expression. "*" and "?" do not match
+// "/", while "**" matches across directories.
+func translate(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			sb.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
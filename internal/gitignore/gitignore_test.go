@@ -0,0 +1,50 @@
+package gitignore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	var m Matcher
+	m.Add("", strings.NewReader(`
+# comment
+*.log
+!keep.log
+/build
+node_modules/
+docs/**/*.tmp
+\#hash
+`))
+	m.Add("pkg", strings.NewReader("local.txt\n/anchored\n"))
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"app.log", false, true},
+		{"deep/dir/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"sub/build", true, false},
+		{"node_modules", true, true},
+		{"node_modules", false, false},
+		{"lib/node_modules", true, true},
+		{"docs/a.tmp", false, true},
+		{"docs/x/y/a.tmp", false, true},
+		{"a.tmp", false, false},
+		{"#hash", false, true},
+		{"pkg/local.txt", false, true},
+		{"pkg/sub/local.txt", false, true},
+		{"local.txt", false, false},
+		{"pkg/anchored", false, true},
+		{"pkg/sub/anchored", false, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.ignored {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.ignored)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+// Package miniyaml parses the subset of YAML used by configuration files such
+// as deployment manifests: block mappings and sequences, plain and quoted
+// scalars, literal and folded block scalars, simple flow sequences and comments.
+// Anchors, tags, multiple documents and nested flow collections are not
+// supported.
+//
+// Values are decoded to map[string]any, []any, string, bool or nil. Only the
+// unquoted scalars true, false, null and ~ are given a non-string type, so that
+// a value like version: 1.10 keeps its exact spelling.
+package miniyaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Unmarshal parses data and stores the result in v, which is decoded with
+// encoding/json rules.
+func Unmarshal(data []byte, v any) error {
+	value, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Parse parses data into generic values.
+func Parse(data []byte) (any, error) {
+	p := &parser{}
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		p.lines = append(p.lines, line{num: i + 1, raw: raw})
+	}
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	first := p.lines[p.pos]
+	if strings.TrimSpace(first.raw) == "---" {
+		p.pos++
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return nil, nil
+		}
+		first = p.lines[p.pos]
+	}
+	value, err := p.block(indentOf(first.raw))
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if p.pos < len(p.lines) {
+		return nil, p.errorf(p.lines[p.pos], "unexpected content")
+	}
+	return value, nil
+}
+
+type line struct {
+	num int
+	raw string
+}
+
+type parser struct {
+	lines []line
+	pos   int
+}
+
+func (p *parser) errorf(l line, format string, args ...any) error {
+	return fmt.Errorf("yaml: line %d: %s", l.num, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipBlank() {
+	for p.pos < len(p.lines) {
+		text := strings.TrimSpace(stripComment(p.lines[p.pos].raw))
+		if text != "" {
+			return
+		}
+		p.pos++
+	}
+}
+
+// block parses a mapping or sequence whose lines are indented by exactly indent.
+func (p *parser) block(indent int) (any, error) {
+	p.skipBlank()
+	l := p.lines[p.pos]
+	if strings.ContainsRune(l.raw[:indentOf(l.raw)], '\t') {
+		return nil, p.errorf(l, "tabs are not allowed in indentation")
+	}
+	text := strings.TrimSpace(stripComment(l.raw))
+	if text == "-" || strings.HasPrefix(text, "- ") {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *parser) mapping(indent int) (any, error) {
+	out := map[string]any{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return out, nil
+		}
+		l := p.lines[p.pos]
+		ind := indentOf(l.raw)
+		if ind < indent {
+			return out, nil
+		}
+		if ind > indent {
+			return nil, p.errorf(l, "unexpected indentation")
+		}
+		text := strings.TrimSpace(stripComment(l.raw))
+		if text == "-" || strings.HasPrefix(text, "- ") {
+			return nil, p.errorf(l, "unexpected sequence item in mapping")
+		}
+		key, rest, ok := splitKey(text)
+		if !ok {
+			return nil, p.errorf(l, "expected \"key: value\"")
+		}
+		if _, dup := out[key]; dup {
+			return nil, p.errorf(l, "duplicate key %q", key)
+		}
+		p.pos++
+		value, err := p.value(l, indent, rest)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = value
+	}
+}
+
+func (p *parser) sequence(indent int) (any, error) {
+	out := []any{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return out, nil
+		}
+		l := p.lines[p.pos]
+		ind := indentOf(l.raw)
+		if ind < indent {
+			return out, nil
+		}
+		if ind > indent {
+			return nil, p.errorf(l, "unexpected indentation")
+		}
+		text := strings.TrimSpace(stripComment(l.raw))
+		if text != "-" && !strings.HasPrefix(text, "- ") {
+			return out, nil
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(text, "-"))
+		if _, _, isMap := splitKey(rest); isMap {
+			// "- key: value" starts a mapping whose keys line up with "key". Replace
+			// the dash with a space and parse the item as that mapping.
+			itemIndent := indent + (len(text) - len(rest))
+			p.lines[p.pos].raw = strings.Repeat(" ", itemIndent) + rest
+			value, err := p.mapping(itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, value)
+			continue
+		}
+		p.pos++
+		value, err := p.value(l, indent, rest)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, value)
+	}
+}
+
+// value parses what follows "key:" or "-" on line l, reading nested lines when
+// the value continues on the following lines.
+func (p *parser) value(l line, indent int, rest string) (any, error) {
+	switch {
+	case rest == "":
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return nil, nil
+		}
+		next := p.lines[p.pos]
+		nextIndent := indentOf(next.raw)
+		nextText := strings.TrimSpace(stripComment(next.raw))
+		// A sequence may sit at the same indentation as its parent key.
+		if nextIndent > indent || (nextIndent == indent && (nextText == "-" || strings.HasPrefix(nextText, "- "))) {
+			return p.block(nextIndent)
+		}
+		return nil, nil
+	case strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">"):
+		return p.blockScalar(indent, rest), nil
+	default:
+		return p.scalar(l, rest)
+	}
+}
+
+func (p *parser) blockScalar(indent int, header string) string {
+	folded := header[0] == '>'
+	chomp := strings.TrimSpace(header[1:])
+	var lines []string
+	blockIndent := -1
+	for p.pos < len(p.lines) {
+		raw := p.lines[p.pos].raw
+		if strings.TrimSpace(raw) == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		ind := indentOf(raw)
+		if blockIndent < 0 {
+			blockIndent = ind
+		}
+		if ind <= indent || ind < blockIndent {
+			break
+		}
+		lines = append(lines, raw[blockIndent:])
+		p.pos++
+	}
+	// Trailing blank lines belong to the chomping, not the content.
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var text string
+	if folded {
+		var sb strings.Builder
+		for i, l := range lines {
+			switch {
+			case i == 0:
+			case l == "" || lines[i-1] == "":
+				sb.WriteString("\n")
+			default:
+				sb.WriteString(" ")
+			}
+			sb.WriteString(l)
+		}
+		text = sb.String()
+	} else {
+		text = strings.Join(lines, "\n")
+	}
+	switch chomp {
+	case "-":
+	case "+":
+		text += "\n" + strings.Repeat("\n", trailing)
+	default:
+		if len(lines) > 0 {
+			text += "\n"
+		}
+	}
+	return text
+}
+
+func (p *parser) scalar(l line, text string) (any, error) {
+	switch {
+	case strings.HasPrefix(text, `"`):
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, p.errorf(l, "invalid double-quoted string")
+		}
+		return s, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, p.errorf(l, "invalid single-quoted string")
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case text == "{}":
+		return map[string]any{}, nil
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, p.errorf(l, "invalid flow sequence")
+		}
+		out := []any{}
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		if inner == "" {
+			return out, nil
+		}
+		for _, item := range strings.Split(inner, ",") {
+			value, err := p.scalar(l, strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, value)
+		}
+		return out, nil
+	case strings.HasPrefix(text, "{"), strings.HasPrefix(text, "&"), strings.HasPrefix(text, "*"), strings.HasPrefix(text, "!"):
+		return nil, p.errorf(l, "unsupported YAML syntax %q", text)
+	}
+	switch text {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+	return text, nil
+}
+
+// splitKey splits "key: value" or "key:" into its parts.
+func splitKey(text string) (key string, rest string, ok bool) {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		quote := text[0]
+		end := strings.IndexByte(text[1:], quote)
+		if end < 0 || !strings.HasPrefix(text[end+2:], ":") {
+			return "", "", false
+		}
+		key = text[1 : end+1]
+		rest = text[end+3:]
+		if rest != "" && rest[0] != ' ' {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(rest), true
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), i > 0
+		}
+	}
+	return "", "", false
+}
+
+func indentOf(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " \t"))
+}
+
+// stripComment removes a trailing comment, ignoring "#" inside quotes or not
+// preceded by whitespace.
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" \t[,", s[i-1]) >= 0):
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
@@ -0,0 +1,71 @@
+package miniyaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc := `
+# deployment
+name: my-app   # trailing comment
+version: 1.10
+force: true
+empty:
+quoted: "a # not a comment"
+single: 'it''s'
+plain: it's fine
+list: [a, "b c"]
+env:
+  A: "1"
+  URL: http://example.com/#frag
+steps:
+- run: build
+  args:
+    - --prod
+- deploy
+script: |
+  line one
+  line two
+folded: >-
+  one
+  two
+`
+	got, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	want := map[string]any{
+		"name":    "my-app",
+		"version": "1.10",
+		"force":   true,
+		"empty":   nil,
+		"quoted":  "a # not a comment",
+		"single":  "it's",
+		"plain":   "it's fine",
+		"list":    []any{"a", "b c"},
+		"env":     map[string]any{"A": "1", "URL": "http://example.com/#frag"},
+		"steps": []any{
+			map[string]any{"run": "build", "args": []any{"--prod"}},
+			"deploy",
+		},
+		"script": "line one\nline two\n",
+		"folded": "one two",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, doc := range []string{
+		"a: 1\n  b: 2\n",
+		"a: 1\na: 2\n",
+		"a: &anchor 1\n",
+		"just text\n",
+	} {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("expected an error for %q", doc)
+		}
+	}
+}
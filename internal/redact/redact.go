@@ -0,0 +1,102 @@
+// Package redact keeps a process-wide set of sensitive values, such as secrets
+// passed as deployment environment variables, and removes them from request and
+// response dumps before they are logged or returned in errors.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// Placeholder replaces redacted values.
+const Placeholder = "[REDACTED]"
+
+var (
+	mu     sync.RWMutex
+	values = map[string]struct{}{}
+	// sorted holds the registered forms longest first, so that a value containing
+	// another registered value is replaced as a whole.
+	sorted []string
+)
+
+// Add registers values as sensitive. Besides the values themselves, their
+// JSON-escaped and URL-encoded forms are redacted. Empty values are ignored.
+func Add(vals ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	changed := false
+	for _, v := range vals {
+		if v == "" {
+			continue
+		}
+		for _, form := range forms(v) {
+			if _, ok := values[form]; !ok {
+				values[form] = struct{}{}
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return
+	}
+	sorted = sorted[:0]
+	for v := range values {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+}
+
+func forms(v string) []string {
+	out := []string{v}
+	if b, err := json.Marshal(v); err == nil {
+		if escaped := string(b[1 : len(b)-1]); escaped != v {
+			out = append(out, escaped)
+		}
+	}
+	if escaped := url.QueryEscape(v); escaped != v {
+		out = append(out, escaped)
+	}
+	return out
+}
+
+// Contains reports whether v has been registered as sensitive.
+func Contains(v string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := values[v]
+	return ok
+}
+
+// Bytes returns b with every sensitive value replaced by [Placeholder]. b is
+// returned unchanged if it contains none.
+func Bytes(b []byte) []byte {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, v := range sorted {
+		if bytes.Contains(b, []byte(v)) {
+			b = bytes.ReplaceAll(b, []byte(v), []byte(Placeholder))
+		}
+	}
+	return b
+}
+
+// String is like [Bytes] for strings.
+func String(s string) string {
+	return string(Bytes([]byte(s)))
+}
+
+// Reset forgets all sensitive values. It is meant for tests.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	values = map[string]struct{}{}
+	sorted = nil
+}
@@ -0,0 +1,16 @@
+package redact
+
+import "testing"
+
+func TestBytes(t *testing.T) {
+	defer Reset()
+	Add("s3cr3t", "a\"b", "", "s3cr3t-long")
+	got := String(`key=s3cr3t other=s3cr3t-long json="a\"b" query=a%22b plain=a`)
+	want := `key=[REDACTED] other=[REDACTED] json="[REDACTED]" query=[REDACTED] plain=a`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if !Contains("s3cr3t") || Contains("") {
+		t.Errorf("unexpected Contains result")
+	}
+}
@@ -0,0 +1,393 @@
+// Package websocket implements the subset of RFC 6455 needed to talk to Chrome
+// DevTools endpoints: the opening handshake for clients and servers, text and
+// binary messages, fragmentation, ping/pong and the closing handshake.
+// Extensions such as per-message compression are not negotiated.
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxMessageSize is the largest message a [Conn] accepts. CDP responses such as
+// full-page screenshots can be tens of megabytes.
+const MaxMessageSize = 256 << 20
+
+// Opcode identifies the type of a frame.
+type Opcode byte
+
+const (
+	OpContinuation Opcode = 0x0
+	OpText         Opcode = 0x1
+	OpBinary       Opcode = 0x2
+	OpClose        Opcode = 0x8
+	OpPing         Opcode = 0x9
+	OpPong         Opcode = 0xa
+)
+
+// Close status codes.
+const (
+	CloseNormal    = 1000
+	CloseGoingAway = 1001
+	CloseProtocol  = 1002
+	CloseTooBig    = 1009
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// CloseError is returned by [Conn.ReadMessage] once the peer has closed the
+// connection.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: closed with status %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: closed with status %d: %s", e.Code, e.Reason)
+}
+
+// Conn is a websocket connection. Reads must not be called concurrently; writes
+// may be.
+type Conn struct {
+	rwc    io.ReadWriteCloser
+	br     *bufio.Reader
+	client bool
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+	closeSent bool
+}
+
+// Doer sends HTTP requests, as *http.Client does.
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// HandshakeError is returned by [Dial] when the server does not switch
+// protocols.
+type HandshakeError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("websocket: handshake failed with status %d: %s", e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+// Dial performs the opening handshake for req, whose URL may use the ws, wss,
+// http or https scheme, using doer to send it. The returned connection stays
+// open when ctx is cancelled after Dial has returned.
+func Dial(ctx context.Context, doer Doer, req *http.Request) (*Conn, error) {
+	req = req.Clone(context.WithoutCancel(ctx))
+	switch req.URL.Scheme {
+	case "ws":
+		req.URL.Scheme = "http"
+	case "wss":
+		req.URL.Scheme = "https"
+	}
+	var keyBytes [16]byte
+	if _, err := rand.Read(keyBytes[:]); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes[:])
+	req.Method = http.MethodGet
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+
+	// The request's own context must outlive the handshake, since cancelling it
+	// would tear down the upgraded connection, so ctx only aborts the handshake.
+	reqCtx, cancel := context.WithCancel(req.Context())
+	stop := context.AfterFunc(ctx, cancel)
+	res, err := doer.Do(req.WithContext(reqCtx))
+	if !stop() {
+		if err == nil {
+			res.Body.Close()
+		}
+		cancel()
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		res.Body.Close()
+		cancel()
+		return nil, &HandshakeError{StatusCode: res.StatusCode, Body: string(body)}
+	}
+	rwc, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		res.Body.Close()
+		cancel()
+		return nil, errors.New("websocket: HTTP client does not support protocol upgrades")
+	}
+	if !strings.EqualFold(res.Header.Get("Upgrade"), "websocket") || res.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		rwc.Close()
+		cancel()
+		return nil, errors.New("websocket: invalid handshake response")
+	}
+	return &Conn{rwc: &cancelCloser{ReadWriteCloser: rwc, cancel: cancel}, br: bufio.NewReader(rwc), client: true}, nil
+}
+
+type cancelCloser struct {
+	io.ReadWriteCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelCloser) Close() error {
+	err := c.ReadWriteCloser.Close()
+	c.cancel()
+	return err
+}
+
+// Accept completes the opening handshake on the server side.
+func Accept(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: not an upgrade request")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("websocket: missing Sec-WebSocket-Key")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("websocket: response does not support hijacking")
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{rwc: conn, br: brw.Reader}, nil
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered and
+// pongs are discarded. Once the peer closes the connection, the close is
+// acknowledged and a [*CloseError] is returned.
+func (c *Conn) ReadMessage() (Opcode, []byte, error) {
+	var msgOp Opcode
+	var msg []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case OpPing:
+			if err := c.writeFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+		case OpPong:
+		case OpClose:
+			closeErr := &CloseError{Code: 1005}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			c.sendClose(CloseNormal, "")
+			c.rwc.Close()
+			return 0, nil, closeErr
+		case OpText, OpBinary, OpContinuation:
+			if op == OpContinuation && msgOp == 0 {
+				return 0, nil, c.fail(CloseProtocol, "unexpected continuation frame")
+			}
+			if op != OpContinuation {
+				if msgOp != 0 {
+					return 0, nil, c.fail(CloseProtocol, "expected continuation frame")
+				}
+				msgOp = op
+			}
+			if len(msg)+len(payload) > MaxMessageSize {
+				return 0, nil, c.fail(CloseTooBig, "message too big")
+			}
+			msg = append(msg, payload...)
+			if fin {
+				if msg == nil {
+					msg = []byte{}
+				}
+				return msgOp, msg, nil
+			}
+		default:
+			return 0, nil, c.fail(CloseProtocol, "unknown opcode")
+		}
+	}
+}
+
+func (c *Conn) fail(code int, reason string) error {
+	c.sendClose(code, reason)
+	c.rwc.Close()
+	return fmt.Errorf("websocket: %s", reason)
+}
+
+func (c *Conn) readFrame() (fin bool, op Opcode, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.br, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		err = c.fail(CloseProtocol, "reserved bits set")
+		return
+	}
+	op = Opcode(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+	if masked == c.client {
+		err = c.fail(CloseProtocol, "invalid frame masking")
+		return
+	}
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= OpClose && (length > 125 || !fin) {
+		err = c.fail(CloseProtocol, "invalid control frame")
+		return
+	}
+	if length > MaxMessageSize {
+		err = c.fail(CloseTooBig, "message too big")
+		return
+	}
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// WriteMessage sends a complete text or binary message.
+func (c *Conn) WriteMessage(op Opcode, data []byte) error {
+	return c.writeFrame(op, data)
+}
+
+func (c *Conn) writeFrame(op Opcode, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	return c.writeFrameLocked(op, payload)
+}
+
+func (c *Conn) writeFrameLocked(op Opcode, payload []byte) error {
+	buf := make([]byte, 0, 14+len(payload))
+	buf = append(buf, 0x80|byte(op))
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, maskBit|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		buf = append(buf, mask[:]...)
+		start := len(buf)
+		buf = append(buf, payload...)
+		for i := range payload {
+			buf[start+i] ^= mask[i%4]
+		}
+	} else {
+		buf = append(buf, payload...)
+	}
+	_, err := c.rwc.Write(buf)
+	return err
+}
+
+func (c *Conn) sendClose(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return
+	}
+	c.closeSent = true
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason...)
+	if nc, ok := c.rwc.(net.Conn); ok {
+		nc.SetWriteDeadline(time.Now().Add(time.Second))
+	}
+	c.writeFrameLocked(OpClose, payload)
+}
+
+// Close sends a close frame, unless one was already exchanged, and closes the
+// underlying connection without waiting for the peer's acknowledgement.
+func (c *Conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.sendClose(CloseNormal, "")
+		err = c.rwc.Close()
+	})
+	return err
+}
@@ -0,0 +1,78 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := Accept(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// A fragmented message interleaved with a ping.
+		conn.writeFrameLockedFragment(OpText, []byte("hel"), false)
+		conn.writeFrame(OpPing, []byte("p"))
+		conn.writeFrameLockedFragment(OpContinuation, []byte("lo"), true)
+		for {
+			op, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(op, append([]byte("echo:"), msg...))
+		}
+	}))
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	req, _ := http.NewRequest(http.MethodGet, wsURL, nil)
+	if _, err := Dial(context.Background(), http.DefaultClient, req); err == nil {
+		t.Fatal("expected the handshake to fail without credentials")
+	} else if herr := (*HandshakeError)(nil); !errors.As(err, &herr) || herr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a HandshakeError, got %v", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer key")
+	conn, err := Dial(context.Background(), http.DefaultClient, req)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	defer conn.Close()
+
+	op, msg, err := conn.ReadMessage()
+	if err != nil || op != OpText || string(msg) != "hello" {
+		t.Fatalf("ReadMessage() = %v, %q, %v", op, msg, err)
+	}
+	big := bytes.Repeat([]byte("x"), 70000)
+	for _, payload := range [][]byte{[]byte("hi"), bytes.Repeat([]byte("y"), 300), big} {
+		if err := conn.WriteMessage(OpBinary, payload); err != nil {
+			t.Fatal(err)
+		}
+		op, msg, err := conn.ReadMessage()
+		if err != nil || op != OpBinary || !bytes.Equal(msg, append([]byte("echo:"), payload...)) {
+			t.Fatalf("unexpected echo of %d bytes: %v, %d bytes, %v", len(payload), op, len(msg), err)
+		}
+	}
+}
+
+// writeFrameLockedFragment writes a single, possibly non-final, frame.
+func (c *Conn) writeFrameLockedFragment(op Opcode, payload []byte, fin bool) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	var b0 byte = byte(op)
+	if fin {
+		b0 |= 0x80
+	}
+	c.rwc.Write(append([]byte{b0, byte(len(payload))}, payload...))
+}
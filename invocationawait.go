@@ -0,0 +1,168 @@
+package kernel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// InvocationCleanupPolicy describes how an invocation is cleaned up when the
+// context of a helper waiting on it is cancelled: the invocation is marked failed
+// through [InvocationService.Update], unless it has finished in the meantime, and the browsers it created are deleted
+// through [InvocationService.DeleteBrowsers]. Without cleanup the invocation
+// keeps running, and its browsers stay alive and billable, after the caller has
+// given up on it.
+//
+// The cleanup requests run on a context detached from the cancelled one and
+// bounded by Timeout, so they complete after the parent has been cancelled.
+type InvocationCleanupPolicy struct {
+	// Reason recorded when the invocation is marked failed. Defaults to "cancelled: "
+	// followed by the cause of the context's cancellation. Updates cannot set the
+	// status reason, so the reason is sent as the invocation's output, rendered as a
+	// JSON string.
+	Reason string
+	// If true, the browsers created by the invocation are left running.
+	KeepBrowsers bool
+	// Upper bound for the cleanup requests. Defaults to 30 seconds.
+	Timeout time.Duration
+	// Called with the error of any failed cleanup request.
+	OnError func(invocationID string, err error)
+}
+
+type InvocationAwaitParams struct {
+	// If set, cancelling the context while waiting cleans up the invocation according
+	// to the policy before returning. If nil, the invocation is left running.
+	CleanupOnCancel *InvocationCleanupPolicy
+}
+
+// Await follows an invocation until it reaches a terminal state and returns its
+// final state. It prefers the event stream and falls back to fetching the
+// invocation if the stream ends early.
+//
+// If ctx is cancelled first, Await returns the context's error, after applying
+// params.CleanupOnCancel if it is set.
+func (r *InvocationService) Await(ctx context.Context, id string, params InvocationAwaitParams, opts ...option.RequestOption) (res *InvocationGetResponse, err error) {
+	if id == "" {
+		return nil, errors.New("missing required id parameter")
+	}
+	res, err = r.await(ctx, id, opts)
+	if err != nil && ctx.Err() != nil && params.CleanupOnCancel != nil {
+		r.cleanup(ctx, id, *params.CleanupOnCancel, opts)
+	}
+	return res, err
+}
+
+// NewAndAwait invokes an action and waits for the invocation to finish. The
+// invocation is always created asynchronously so that its ID is known, and so
+// can be cleaned up, even if ctx is cancelled while the action is running.
+func (r *InvocationService) NewAndAwait(ctx context.Context, body InvocationNewParams, params InvocationAwaitParams, opts ...option.RequestOption) (res *InvocationGetResponse, err error) {
+	body.Async = Bool(true)
+	inv, err := r.New(ctx, body, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return r.Await(ctx, inv.ID, params, opts...)
+}
+
+// Cleanup marks an invocation failed if it is still queued or running and, unless
+// the policy keeps them, deletes the browsers it created. An invocation that has
+// already finished keeps its status and output. It runs on a context detached
+// from ctx, so it may be called with a context that is already cancelled. Both
+// steps are attempted even if the first fails; their errors are joined.
+func (r *InvocationService) Cleanup(ctx context.Context, id string, policy InvocationCleanupPolicy, opts ...option.RequestOption) error {
+	if id == "" {
+		return errors.New("missing required id parameter")
+	}
+	return r.cleanup(ctx, id, policy, opts)
+}
+
+func (r *InvocationService) cleanup(ctx context.Context, id string, policy InvocationCleanupPolicy, opts []option.RequestOption) error {
+	reason := policy.Reason
+	if reason == "" {
+		reason = "cancelled"
+		if cause := context.Cause(ctx); cause != nil {
+			reason = "cancelled: " + cause.Error()
+		}
+	}
+	timeout := policy.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	report := func(step string, err error) error {
+		if err == nil {
+			return nil
+		}
+		err = fmt.Errorf("kernel: %s for invocation %s: %w", step, id, err)
+		if policy.OnError != nil {
+			policy.OnError(id, err)
+		}
+		return err
+	}
+
+	// The invocation may have finished while the caller was giving up on it, and
+	// marking it failed would overwrite its output.
+	inv, err := r.Get(cleanupCtx, id, opts...)
+	errs := []error{report("checking status", err)}
+	if err == nil && !isTerminalInvocationStatus(string(inv.Status)) {
+		output, _ := json.Marshal(reason)
+		_, err = r.Update(cleanupCtx, id, InvocationUpdateParams{
+			Status: InvocationUpdateParamsStatusFailed,
+			Output: String(string(output)),
+		}, opts...)
+		errs = append(errs, report("marking failed", err))
+	}
+	if !policy.KeepBrowsers {
+		errs = append(errs, report("deleting browsers", r.DeleteBrowsers(cleanupCtx, id, opts...)))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *InvocationService) await(ctx context.Context, id string, opts []option.RequestOption) (*InvocationGetResponse, error) {
+	for {
+		stream := r.FollowStreaming(ctx, id, InvocationFollowParams{}, opts...)
+		for stream.Next() {
+			event := stream.Current()
+			if event.Event != "invocation_state" {
+				continue
+			}
+			state := event.AsInvocationState().Invocation
+			if isTerminalInvocationStatus(state.Status) {
+				stream.Close()
+				return &InvocationGetResponse{
+					ID:           state.ID,
+					ActionName:   state.ActionName,
+					AppName:      state.AppName,
+					StartedAt:    state.StartedAt,
+					Status:       InvocationGetResponseStatus(state.Status),
+					Version:      state.Version,
+					FinishedAt:   state.FinishedAt,
+					Output:       state.Output,
+					Payload:      state.Payload,
+					StatusReason: state.StatusReason,
+				}, nil
+			}
+		}
+		stream.Close()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		inv, err := r.Get(ctx, id, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if isTerminalInvocationStatus(string(inv.Status)) {
+			return inv, nil
+		}
+		if err := sleepContext(ctx, time.Second); err != nil {
+			return nil, err
+		}
+	}
+}
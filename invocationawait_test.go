@@ -0,0 +1,110 @@
+package kernel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/lib/action"
+	"github.com/kernel/kernel-go-sdk/lib/kerneltest"
+)
+
+func newBlockingServer(t *testing.T, started chan<- string) *kerneltest.Server {
+	app := action.NewApp("app")
+	action.Register(app, "block", func(ctx context.Context, in struct{}) (struct{}, error) {
+		started <- "started"
+		<-ctx.Done()
+		return struct{}{}, ctx.Err()
+	})
+	action.Register(app, "echo", func(ctx context.Context, in string) (string, error) {
+		return in, nil
+	})
+	srv := kerneltest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.Deploy(app, "1")
+	return srv
+}
+
+func TestInvocationNewAndAwait(t *testing.T) {
+	client := newBlockingServer(t, nil).Client()
+	res, err := client.Invocations.NewAndAwait(context.Background(), kernel.InvocationNewParams{
+		AppName:    "app",
+		ActionName: "echo",
+		Version:    "1",
+		Payload:    kernel.String(`"hi"`),
+	}, kernel.InvocationAwaitParams{})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.Status != kernel.InvocationGetResponseStatusSucceeded || res.Output != `"hi"` {
+		t.Errorf("unexpected invocation: %+v", res)
+	}
+}
+
+func TestInvocationAwaitCleanupOnCancel(t *testing.T) {
+	started := make(chan string, 1)
+	srv := newBlockingServer(t, started)
+	client := srv.Client()
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	inv, err := client.Invocations.New(ctx, kernel.InvocationNewParams{
+		AppName:    "app",
+		ActionName: "block",
+		Version:    "1",
+		Async:      kernel.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	go func() {
+		<-started
+		cancel(errors.New("caller gave up"))
+	}()
+	var cleanupErr error
+	_, err = client.Invocations.Await(ctx, inv.ID, kernel.InvocationAwaitParams{
+		CleanupOnCancel: &kernel.InvocationCleanupPolicy{
+			Timeout: 5 * time.Second,
+			OnError: func(id string, err error) { cleanupErr = err },
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if cleanupErr != nil {
+		t.Fatalf("cleanup should succeed: %s", cleanupErr.Error())
+	}
+
+	state, _ := srv.Invocation(inv.ID)
+	if state.Status != action.StatusFailed || state.Output != `"cancelled: caller gave up"` {
+		t.Errorf("expected the invocation to be failed with the cancellation cause, got %+v", state)
+	}
+	if srv.BrowsersDeleted(inv.ID) != 1 {
+		t.Errorf("expected the invocation's browsers to be deleted")
+	}
+}
+
+func TestInvocationCleanupKeepsFinishedInvocations(t *testing.T) {
+	srv := newBlockingServer(t, nil)
+	client := srv.Client()
+	inv, err := client.Invocations.New(context.Background(), kernel.InvocationNewParams{
+		AppName:    "app",
+		ActionName: "echo",
+		Version:    "1",
+		Payload:    kernel.String(`"hi"`),
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if err := client.Invocations.Cleanup(context.Background(), inv.ID, kernel.InvocationCleanupPolicy{}); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	state, _ := srv.Invocation(inv.ID)
+	if state.Status != action.StatusSucceeded || state.Output != `"hi"` {
+		t.Errorf("expected the finished invocation to keep its output, got %+v", state)
+	}
+	if srv.BrowsersDeleted(inv.ID) != 1 {
+		t.Errorf("expected the invocation's browsers to be deleted")
+	}
+}
@@ -0,0 +1,492 @@
+package kernel
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// InvocationBatchInput is a single item to invoke as part of a batch.
+type InvocationBatchInput struct {
+	// Key identifies the item within the batch and is recorded in the progress
+	// journal so an interrupted batch can be resumed. If empty, the item's position
+	// in the input sequence is used.
+	Key string
+	// Input data for the action, sent as a JSON string.
+	Payload string
+}
+
+// InvocationBatchInputs iterates over the inputs of a batch. It has the same
+// shape as the SDK's auto-pagers and streams, so an existing iterator can be
+// adapted without buffering every input in memory.
+type InvocationBatchInputs interface {
+	Next() bool
+	Current() InvocationBatchInput
+	Err() error
+}
+
+// NewInvocationBatchInputs returns an [InvocationBatchInputs] over a fixed set of
+// inputs.
+func NewInvocationBatchInputs(inputs ...InvocationBatchInput) InvocationBatchInputs {
+	return &sliceBatchInputs{inputs: inputs, idx: -1}
+}
+
+type sliceBatchInputs struct {
+	inputs []InvocationBatchInput
+	idx    int
+}
+
+func (s *sliceBatchInputs) Next() bool {
+	s.idx++
+	return s.idx < len(s.inputs)
+}
+
+func (s *sliceBatchInputs) Current() InvocationBatchInput { return s.inputs[s.idx] }
+func (s *sliceBatchInputs) Err() error                    { return nil }
+
+type InvocationBatchParams struct {
+	// Name of the action to invoke
+	ActionName string
+	// Name of the application
+	AppName string
+	// Version of the application
+	Version string
+	// If true, each item is invoked asynchronously and the runner follows the
+	// invocation until it reaches a terminal state. Outstanding async invocations
+	// are cancelled when the batch context is cancelled.
+	Async bool
+	// Timeout in seconds for async invocations (min 10, max 3600). Only applies when
+	// Async is true.
+	AsyncTimeoutSeconds int64
+	// How outstanding async invocations are cleaned up when the batch context is
+	// cancelled. The zero value marks them failed and deletes their browsers.
+	Cleanup InvocationCleanupPolicy
+	// Maximum number of invocations in flight at once. Defaults to 4.
+	Concurrency int
+	// Maximum number of invocations started per second. Zero means unlimited.
+	RatePerSecond float64
+	// Maximum number of attempts per item, including the first. Creating an
+	// invocation is not idempotent, so only failures where the request certainly
+	// did not start one (429 responses and refused connections) are retried. Other
+	// errors, and invocations that run and report "failed", are recorded as
+	// failed. Defaults to 3.
+	MaxAttempts int
+	// Base delay between attempts, doubled on each retry and capped at 30 seconds.
+	// Defaults to 1 second.
+	RetryBackoff time.Duration
+	// Path of a JSON-lines file recording the outcome of every finished item. If the
+	// file already exists, items whose key it records as succeeded are skipped, so
+	// re-running an interrupted batch with the same journal resumes it.
+	JournalPath string
+	// Called after each item reaches a final status. Calls may come from several
+	// goroutines at once.
+	OnProgress func(InvocationBatchItemResult)
+}
+
+// Final status of an item in an invocation batch.
+type InvocationBatchItemStatus string
+
+const (
+	InvocationBatchItemStatusSucceeded InvocationBatchItemStatus = "succeeded"
+	InvocationBatchItemStatusFailed    InvocationBatchItemStatus = "failed"
+	InvocationBatchItemStatusCancelled InvocationBatchItemStatus = "cancelled"
+	InvocationBatchItemStatusSkipped   InvocationBatchItemStatus = "skipped"
+)
+
+// InvocationBatchItemResult is the outcome of a single batch item. It is also the
+// record written to the progress journal.
+type InvocationBatchItemResult struct {
+	Key    string                    `json:"key"`
+	Status InvocationBatchItemStatus `json:"status"`
+	// ID of the last invocation made for the item, if any.
+	InvocationID string `json:"invocation_id,omitempty"`
+	// Output produced by the action, rendered as a JSON string.
+	Output       string `json:"output,omitempty"`
+	StatusReason string `json:"status_reason,omitempty"`
+	// Error that prevented the item from completing, if any.
+	Error      string    `json:"error,omitempty"`
+	Attempts   int       `json:"attempts"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// InvocationBatchResult aggregates the outcome of an invocation batch. Items are
+// in input order.
+type InvocationBatchResult struct {
+	Items     []InvocationBatchItemResult
+	Succeeded int
+	Failed    int
+	Cancelled int
+	Skipped   int
+}
+
+// RunBatch invokes an action once per input, with bounded concurrency and an
+// optional rate limit. It returns once every input has been processed or the
+// context is cancelled. On cancellation, outstanding async invocations are marked
+// failed and their browsers deleted, inputs already read but not yet started are
+// reported as cancelled, no further inputs are read, and the result is returned
+// together with the context's error.
+func (r *InvocationService) RunBatch(ctx context.Context, params InvocationBatchParams, inputs InvocationBatchInputs, opts ...option.RequestOption) (res *InvocationBatchResult, err error) {
+	if params.ActionName == "" || params.AppName == "" || params.Version == "" {
+		return nil, errors.New("missing required action_name, app_name or version parameter")
+	}
+	if params.Concurrency <= 0 {
+		params.Concurrency = 4
+	}
+	if params.MaxAttempts <= 0 {
+		params.MaxAttempts = 3
+	}
+	if params.RetryBackoff <= 0 {
+		params.RetryBackoff = time.Second
+	}
+
+	journal, err := openInvocationBatchJournal(params.JournalPath)
+	if err != nil {
+		return nil, err
+	}
+	defer journal.Close()
+
+	type job struct {
+		idx   int
+		input InvocationBatchInput
+	}
+	type done struct {
+		idx    int
+		result InvocationBatchItemResult
+	}
+
+	jobs := make(chan job)
+	results := make(chan done)
+	limiter := newRateLimiter(params.RatePerSecond)
+
+	var workers sync.WaitGroup
+	for i := 0; i < params.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for j := range jobs {
+				result := r.runBatchItem(ctx, params, limiter, j.input, opts)
+				if result.Status != InvocationBatchItemStatusCancelled {
+					journal.record(result)
+				}
+				if params.OnProgress != nil {
+					params.OnProgress(result)
+				}
+				results <- done{j.idx, result}
+			}
+		}()
+	}
+
+	var inputErr error
+	go func() {
+		defer close(jobs)
+		for idx := 0; ctx.Err() == nil && inputs.Next(); idx++ {
+			input := inputs.Current()
+			if input.Key == "" {
+				input.Key = strconv.Itoa(idx)
+			}
+			if prev, ok := journal.succeeded(input.Key); ok {
+				prev.Status = InvocationBatchItemStatusSkipped
+				if params.OnProgress != nil {
+					params.OnProgress(prev)
+				}
+				results <- done{idx, prev}
+				continue
+			}
+			if ctx.Err() == nil {
+				select {
+				case jobs <- job{idx, input}:
+					continue
+				case <-ctx.Done():
+				}
+			}
+			// The input was read but never started. It is still reported, but no
+			// further inputs are read: the iterator may never end.
+			result := InvocationBatchItemResult{
+				Key:        input.Key,
+				Status:     InvocationBatchItemStatusCancelled,
+				Error:      ctx.Err().Error(),
+				FinishedAt: time.Now(),
+			}
+			if params.OnProgress != nil {
+				params.OnProgress(result)
+			}
+			results <- done{idx, result}
+			return
+		}
+		inputErr = inputs.Err()
+	}()
+
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	var collected []done
+	for d := range results {
+		collected = append(collected, d)
+	}
+	sort.Slice(collected, func(i, j int) bool { return collected[i].idx < collected[j].idx })
+
+	res = &InvocationBatchResult{}
+	for _, d := range collected {
+		res.Items = append(res.Items, d.result)
+		switch d.result.Status {
+		case InvocationBatchItemStatusSucceeded:
+			res.Succeeded++
+		case InvocationBatchItemStatusFailed:
+			res.Failed++
+		case InvocationBatchItemStatusCancelled:
+			res.Cancelled++
+		case InvocationBatchItemStatusSkipped:
+			res.Skipped++
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	if inputErr != nil {
+		return res, inputErr
+	}
+	return res, journal.err()
+}
+
+func (r *InvocationService) runBatchItem(ctx context.Context, params InvocationBatchParams, limiter *rateLimiter, input InvocationBatchInput, opts []option.RequestOption) (result InvocationBatchItemResult) {
+	result.Key = input.Key
+	defer func() { result.FinishedAt = time.Now() }()
+
+	body := InvocationNewParams{
+		ActionName: params.ActionName,
+		AppName:    params.AppName,
+		Version:    params.Version,
+	}
+	if input.Payload != "" {
+		body.Payload = String(input.Payload)
+	}
+	if params.Async {
+		body.Async = Bool(true)
+		if params.AsyncTimeoutSeconds > 0 {
+			body.AsyncTimeoutSeconds = Int(params.AsyncTimeoutSeconds)
+		}
+	}
+
+	// The client's own retries would re-send a create that may have reached the
+	// server, so they are disabled and only unsent requests are retried here.
+	createOpts := slices.Concat(opts, []option.RequestOption{option.WithMaxRetries(0)})
+	var lastErr error
+	for attempt := 1; attempt <= params.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			if err := sleepContext(ctx, retryBackoff(params.RetryBackoff, attempt-1)); err != nil {
+				break
+			}
+		}
+		if err := limiter.wait(ctx); err != nil {
+			break
+		}
+		result.Attempts = attempt
+
+		inv, err := r.New(ctx, body, createOpts...)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() == nil && isUnsentError(err) {
+				continue
+			}
+			break
+		}
+		result.InvocationID = inv.ID
+
+		status, output, reason := string(inv.Status), inv.Output, inv.StatusReason
+		if !isTerminalInvocationStatus(status) {
+			final, err := r.Await(ctx, inv.ID, InvocationAwaitParams{CleanupOnCancel: &params.Cleanup}, opts...)
+			if err != nil {
+				lastErr = err
+				break
+			}
+			status, output, reason = string(final.Status), final.Output, final.StatusReason
+		}
+
+		result.Output = output
+		result.StatusReason = reason
+		if status == string(InvocationGetResponseStatusSucceeded) {
+			result.Status = InvocationBatchItemStatusSucceeded
+		} else {
+			result.Status = InvocationBatchItemStatusFailed
+		}
+		return
+	}
+
+	if ctx.Err() != nil {
+		result.Status = InvocationBatchItemStatusCancelled
+		result.Error = ctx.Err().Error()
+		return
+	}
+	result.Status = InvocationBatchItemStatusFailed
+	if lastErr != nil {
+		result.Error = lastErr.Error()
+	}
+	return
+}
+
+func isTerminalInvocationStatus(status string) bool {
+	return status == string(InvocationGetResponseStatusSucceeded) || status == string(InvocationGetResponseStatusFailed)
+}
+
+// isTransientError reports whether err is worth retrying: a network error or an
+// API error with the same status codes the client itself retries.
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apierr *Error
+	if errors.As(err, &apierr) {
+		code := apierr.StatusCode
+		return code == http.StatusRequestTimeout ||
+			code == http.StatusConflict ||
+			code == http.StatusTooManyRequests ||
+			code >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isUnsentError reports whether err shows that a request was rejected before
+// the server acted on it, so that retrying a non-idempotent request cannot
+// repeat it: a 429 response or a refused connection.
+func isUnsentError(err error) bool {
+	var apierr *Error
+	if errors.As(err, &apierr) {
+		return apierr.StatusCode == http.StatusTooManyRequests
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+func retryBackoff(base time.Duration, retry int) time.Duration {
+	delay := time.Duration(float64(base) * math.Pow(2, float64(retry-1)))
+	if delay > 30*time.Second || delay <= 0 {
+		delay = 30 * time.Second
+	}
+	return delay
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimiter spaces out events so no more than a fixed number start per second.
+// A nil rateLimiter never waits.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+	return sleepContext(ctx, time.Until(at))
+}
+
+type invocationBatchJournal struct {
+	mu       sync.Mutex
+	file     *os.File
+	previous map[string]InvocationBatchItemResult
+	writeErr error
+}
+
+func openInvocationBatchJournal(path string) (*invocationBatchJournal, error) {
+	j := &invocationBatchJournal{previous: map[string]InvocationBatchItemResult{}}
+	if path == "" {
+		return j, nil
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("kernel: opening batch journal: %w", err)
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var rec InvocationBatchItemResult
+		// A partially written trailing line from an interrupted run is ignored.
+		if json.Unmarshal(scanner.Bytes(), &rec) != nil {
+			continue
+		}
+		j.previous[rec.Key] = rec
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("kernel: reading batch journal: %w", err)
+	}
+	j.file = f
+	return j, nil
+}
+
+func (j *invocationBatchJournal) succeeded(key string) (InvocationBatchItemResult, bool) {
+	rec, ok := j.previous[key]
+	return rec, ok && rec.Status == InvocationBatchItemStatusSucceeded
+}
+
+func (j *invocationBatchJournal) record(result InvocationBatchItemResult) {
+	if j.file == nil {
+		return
+	}
+	line, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(line, '\n')); err != nil && j.writeErr == nil {
+		j.writeErr = fmt.Errorf("kernel: writing batch journal: %w", err)
+	}
+}
+
+func (j *invocationBatchJournal) err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.writeErr
+}
+
+func (j *invocationBatchJournal) Close() error {
+	if j.file == nil {
+		return nil
+	}
+	return j.file.Close()
+}
@@ -0,0 +1,218 @@
+package kernel_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+func TestInvocationRunBatch(t *testing.T) {
+	var calls atomic.Int32
+	var failedOnce sync.Map
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/invocations" {
+			http.NotFound(w, r)
+			return
+		}
+		calls.Add(1)
+		var body struct {
+			Payload string `json:"payload"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if _, loaded := failedOnce.LoadOrStore(body.Payload, true); !loaded {
+			switch body.Payload {
+			case `{"n":1}`:
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, `{"code":"rate_limited","message":"slow down"}`)
+				return
+			case `{"n":3}`:
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprint(w, `{"code":"unavailable","message":"try again"}`)
+				return
+			}
+		}
+		status := "succeeded"
+		if body.Payload == `{"n":2}` {
+			status = "failed"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"inv_%d","action_name":"scrape","status":%q,"output":%q}`, calls.Load(), status, body.Payload)
+	}), option.WithMaxRetries(0))
+
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+	params := kernel.InvocationBatchParams{
+		AppName:      "app",
+		ActionName:   "scrape",
+		Version:      "1",
+		Concurrency:  2,
+		RetryBackoff: time.Millisecond,
+		JournalPath:  journal,
+	}
+	inputs := func() kernel.InvocationBatchInputs {
+		return kernel.NewInvocationBatchInputs(
+			kernel.InvocationBatchInput{Payload: `{"n":0}`},
+			kernel.InvocationBatchInput{Payload: `{"n":1}`},
+			kernel.InvocationBatchInput{Payload: `{"n":2}`},
+			kernel.InvocationBatchInput{Payload: `{"n":3}`},
+		)
+	}
+
+	res, err := client.Invocations.RunBatch(context.Background(), params, inputs())
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.Succeeded != 2 || res.Failed != 2 {
+		t.Fatalf("expected 2 succeeded and 2 failed, got %+v", res)
+	}
+	if res.Items[1].Attempts != 2 || res.Items[1].Output != `{"n":1}` {
+		t.Errorf("expected item 1 to succeed on retry, got %+v", res.Items[1])
+	}
+	// The request may have created an invocation, so it is not retried.
+	if res.Items[3].Attempts != 1 || res.Items[3].Status != kernel.InvocationBatchItemStatusFailed {
+		t.Errorf("expected item 3 to fail without a retry, got %+v", res.Items[3])
+	}
+
+	data, err := os.ReadFile(journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 4 {
+		t.Errorf("expected 4 journal records, got %d", n)
+	}
+
+	calls.Store(0)
+	res, err = client.Invocations.RunBatch(context.Background(), params, inputs())
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.Skipped != 2 || res.Failed != 1 || res.Succeeded != 1 || calls.Load() != 2 {
+		t.Errorf("expected resume to skip succeeded items, got %+v after %d calls", res, calls.Load())
+	}
+}
+
+func TestInvocationRunBatchCancelsAsyncInvocations(t *testing.T) {
+	started := make(chan struct{})
+	var updated, deleted atomic.Bool
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/invocations":
+			fmt.Fprint(w, `{"id":"inv_1","action_name":"scrape","status":"queued"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/invocations/inv_1/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.(http.Flusher).Flush()
+			close(started)
+			<-r.Context().Done()
+		case r.Method == http.MethodGet && r.URL.Path == "/invocations/inv_1":
+			fmt.Fprint(w, `{"id":"inv_1","action_name":"scrape","status":"running"}`)
+		case r.Method == http.MethodPatch && r.URL.Path == "/invocations/inv_1":
+			updated.Store(true)
+			fmt.Fprint(w, `{"id":"inv_1","status":"failed"}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/invocations/inv_1/browsers":
+			deleted.Store(true)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}), option.WithMaxRetries(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	res, err := client.Invocations.RunBatch(ctx, kernel.InvocationBatchParams{
+		AppName:    "app",
+		ActionName: "scrape",
+		Version:    "1",
+		Async:      true,
+	}, kernel.NewInvocationBatchInputs(kernel.InvocationBatchInput{Key: "only"}))
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if res.Cancelled != 1 || res.Items[0].InvocationID != "inv_1" {
+		t.Errorf("expected the item to be cancelled, got %+v", res)
+	}
+	if !updated.Load() || !deleted.Load() {
+		t.Errorf("expected the invocation to be failed and its browsers deleted")
+	}
+}
+
+// endlessBatchInputs never runs out of inputs.
+type endlessBatchInputs struct{ read atomic.Int32 }
+
+func (e *endlessBatchInputs) Next() bool { e.read.Add(1); return true }
+func (e *endlessBatchInputs) Current() kernel.InvocationBatchInput {
+	return kernel.InvocationBatchInput{Key: fmt.Sprint(e.read.Load())}
+}
+func (e *endlessBatchInputs) Err() error { return nil }
+
+func TestInvocationRunBatchStopsReadingOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	release := make(chan struct{})
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-release
+	}), option.WithMaxRetries(0))
+	defer close(release)
+
+	inputs := &endlessBatchInputs{}
+	res, err := client.Invocations.RunBatch(ctx, kernel.InvocationBatchParams{
+		AppName:     "app",
+		ActionName:  "scrape",
+		Version:     "1",
+		Concurrency: 1,
+	}, inputs)
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(res.Items) != int(inputs.read.Load()) || res.Cancelled != len(res.Items) {
+		t.Fatalf("expected every input read to be reported cancelled, got %+v after %d reads", res, inputs.read.Load())
+	}
+	for i, item := range res.Items {
+		if item.Key != fmt.Sprint(i+1) || item.Status != kernel.InvocationBatchItemStatusCancelled {
+			t.Errorf("unexpected item %d: %+v", i, item)
+		}
+	}
+	if res.Items[len(res.Items)-1].Attempts != 0 {
+		t.Errorf("expected the last item never to be attempted, got %+v", res.Items[len(res.Items)-1])
+	}
+}
+
+func TestInvocationRunBatchDoesNotResendCreates(t *testing.T) {
+	var calls atomic.Int32
+	// The client keeps its default retries.
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"code":"unavailable","message":"try again"}`)
+	}))
+
+	res, err := client.Invocations.RunBatch(context.Background(), kernel.InvocationBatchParams{
+		AppName:      "app",
+		ActionName:   "scrape",
+		Version:      "1",
+		RetryBackoff: time.Millisecond,
+	}, kernel.NewInvocationBatchInputs(kernel.InvocationBatchInput{Key: "a"}))
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if calls.Load() != 1 || res.Failed != 1 {
+		t.Errorf("expected exactly one create request, got %d calls and %+v", calls.Load(), res)
+	}
+}
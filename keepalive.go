@@ -0,0 +1,118 @@
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/lib/cdp"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// BrowserInactivityCheckInterval is how often the API checks sessions for
+// inactivity, so a session is terminated up to this long before or after its
+// TimeoutSeconds have elapsed.
+const BrowserInactivityCheckInterval = 5 * time.Second
+
+// KeepaliveParams configures [Session.Keepalive].
+type KeepaliveParams struct {
+	// Interval between keepalive ticks. Defaults to [KeepaliveInterval] of the
+	// session's TimeoutSeconds.
+	Interval time.Duration
+	// Activity is run on every tick to keep the session active. By default it
+	// opens a CDP connection to the session and closes it again, which counts as
+	// activity without affecting open pages.
+	Activity func(ctx context.Context, session *Session) error
+	// OnGone is called once, and the keepalive stops, when the session no longer
+	// exists.
+	OnGone func(err error)
+	// OnError is called for any other error of a tick. The keepalive carries on.
+	OnError func(err error)
+}
+
+// KeepaliveInterval returns the default keepalive interval for a session that
+// times out after timeoutSeconds of inactivity. It leaves room for two ticks
+// before the earliest time the session could be terminated.
+func KeepaliveInterval(timeoutSeconds int64) time.Duration {
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = 60
+	}
+	interval := (time.Duration(timeoutSeconds)*time.Second - BrowserInactivityCheckInterval) / 2
+	return max(interval, time.Second)
+}
+
+// Keepalive keeps the session from timing out while the caller is idle. On
+// every tick it checks that the session still exists and then runs the
+// activity. It runs in the background until ctx is done, the returned stop
+// function is called, the session is deleted through [Session.Delete] or
+// [BrowserService.With], or the session is found to be gone. Starting a new
+// keepalive stops the previous one.
+func (s *Session) Keepalive(ctx context.Context, params KeepaliveParams, opts ...option.RequestOption) (stop func()) {
+	interval := params.Interval
+	if interval <= 0 {
+		interval = KeepaliveInterval(s.Browser().TimeoutSeconds)
+	}
+	activity := params.Activity
+	if activity == nil {
+		activity = func(ctx context.Context, session *Session) error {
+			return session.touch(ctx, opts...)
+		}
+	}
+
+	id := s.ID()
+	ctx, cancel := context.WithCancel(ctx)
+	s.keepaliveMu.Lock()
+	if s.stopKeepalive != nil {
+		s.stopKeepalive()
+	}
+	s.stopKeepalive = cancel
+	s.keepaliveMu.Unlock()
+
+	go func() {
+		defer cancel()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			_, err := s.browsers.Get(ctx, id, BrowserGetParams{}, opts...)
+			if err == nil {
+				err = activity(ctx, s)
+			}
+			switch {
+			case err == nil || ctx.Err() != nil:
+			case isNotFound(err):
+				if params.OnGone != nil {
+					params.OnGone(err)
+				}
+				return
+			case params.OnError != nil:
+				params.OnError(fmt.Errorf("kernel: keepalive for browser session %s: %w", id, err))
+			}
+		}
+	}()
+	return cancel
+}
+
+// touch opens and closes a CDP connection, which the API counts as activity.
+func (s *Session) touch(ctx context.Context, opts ...option.RequestOption) error {
+	conn, err := cdp.Dial(ctx, s.Browser().CdpWsURL, slices.Concat(s.browsers.Options, opts)...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Call(ctx, "Browser.getVersion", nil, nil)
+}
+
+func (s *Session) keepaliveDone() {
+	s.keepaliveMu.Lock()
+	defer s.keepaliveMu.Unlock()
+	if s.stopKeepalive != nil {
+		s.stopKeepalive()
+		s.stopKeepalive = nil
+	}
+}
@@ -0,0 +1,189 @@
+package kernel_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/lib/cdp/cdptest"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+func TestKeepaliveInterval(t *testing.T) {
+	for timeout, want := range map[int64]time.Duration{
+		10:  2500 * time.Millisecond,
+		60:  27500 * time.Millisecond,
+		0:   27500 * time.Millisecond,
+		600: 297500 * time.Millisecond,
+	} {
+		if got := kernel.KeepaliveInterval(timeout); got != want {
+			t.Errorf("KeepaliveInterval(%d) = %s, want %s", timeout, got, want)
+		}
+	}
+}
+
+type keepaliveServer struct {
+	mu      sync.Mutex
+	gets    int
+	goneAt  int
+	deleted bool
+}
+
+func (s *keepaliveServer) getCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gets
+}
+
+func newKeepaliveServer(t *testing.T, cdpURL string) (*keepaliveServer, kernel.Client) {
+	s := &keepaliveServer{}
+	body := fmt.Sprintf(`{"session_id":"sess_1","cdp_ws_url":%q,"created_at":"2025-01-01T00:00:00Z","headless":true,"stealth":false,"timeout_seconds":60}`, cdpURL)
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/browsers":
+			fmt.Fprint(w, body)
+		case r.Method == http.MethodGet && r.URL.Path == "/browsers/sess_1":
+			s.gets++
+			if s.deleted || (s.goneAt > 0 && s.gets >= s.goneAt) {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"code":"not_found","message":"browser not found"}`)
+				return
+			}
+			fmt.Fprint(w, body)
+		case r.Method == http.MethodDelete && r.URL.Path == "/browsers/sess_1":
+			s.deleted = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}), option.WithMaxRetries(0))
+	return s, client
+}
+
+func TestKeepalive(t *testing.T) {
+	browser := cdptest.NewServer()
+	defer browser.Close()
+	browser.Handle("Browser.getVersion", func(cdptest.Call) (any, error) { return nil, nil })
+	s, client := newKeepaliveServer(t, browser.URL())
+	s.goneAt = 4
+	ctx := context.Background()
+
+	session, err := client.Browsers.NewSession(ctx, kernel.BrowserNewParams{})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	gone := make(chan error, 1)
+	session.Keepalive(ctx, kernel.KeepaliveParams{
+		Interval: 5 * time.Millisecond,
+		OnGone:   func(err error) { gone <- err },
+		OnError:  func(err error) { t.Errorf("unexpected keepalive error: %v", err) },
+	})
+
+	select {
+	case err := <-gone:
+		var apierr *kernel.Error
+		if !errors.As(err, &apierr) || apierr.StatusCode != http.StatusNotFound {
+			t.Errorf("expected a not found error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for OnGone")
+	}
+	if n := len(browser.Calls()); n != 3 {
+		t.Errorf("expected a CDP round trip on each tick before the session went away, got %d", n)
+	}
+	if got := browser.Header().Get("Authorization"); got != "" {
+		t.Errorf("expected the API key not to be sent to the browser, got %q", got)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := s.getCount(); n != 4 {
+		t.Errorf("expected the keepalive to stop once the session is gone, got %d checks", n)
+	}
+}
+
+func TestKeepaliveStopsOnDelete(t *testing.T) {
+	s, client := newKeepaliveServer(t, "ws://127.0.0.1:0")
+	ctx := context.Background()
+	session, err := client.Browsers.NewSession(ctx, kernel.BrowserNewParams{})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	session.Keepalive(ctx, kernel.KeepaliveParams{
+		Interval: time.Millisecond,
+		Activity: func(ctx context.Context, session *kernel.Session) error { return nil },
+		OnGone:   func(err error) { t.Error("OnGone should not be called after Delete") },
+	})
+	for s.getCount() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := session.Delete(ctx); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestKeepaliveRefresh(t *testing.T) {
+	browser := cdptest.NewServer()
+	defer browser.Close()
+	browser.Handle("Browser.getVersion", func(cdptest.Call) (any, error) { return nil, nil })
+	_, client := newKeepaliveServer(t, browser.URL())
+	ctx := context.Background()
+	session, err := client.Browsers.NewSession(ctx, kernel.BrowserNewParams{})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	stop := session.Keepalive(ctx, kernel.KeepaliveParams{
+		Interval: time.Millisecond,
+		OnError:  func(err error) { t.Errorf("unexpected keepalive error: %v", err) },
+	})
+	defer stop()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			_ = session.Browser().CdpWsURL
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	// Run with -race: refreshing must not race with the keepalive's ticks or
+	// with other readers.
+	for i := 0; i < 20; i++ {
+		if err := session.Refresh(ctx); err != nil {
+			t.Fatalf("err should be nil: %s", err.Error())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	<-done
+}
+
+func TestKeepaliveSharedClientOptions(t *testing.T) {
+	browser := cdptest.NewServer()
+	defer browser.Close()
+	browser.Handle("Browser.getVersion", func(cdptest.Call) (any, error) { return nil, nil })
+	_, client := newKeepaliveServer(t, browser.URL())
+	// Spare capacity lets an append to the client's options write into a backing
+	// array shared by every keepalive.
+	client.Browsers.Options = slices.Grow(client.Browsers.Options, 4)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		session, err := client.Browsers.NewSession(ctx, kernel.BrowserNewParams{})
+		if err != nil {
+			t.Fatalf("err should be nil: %s", err.Error())
+		}
+		stop := session.Keepalive(ctx, kernel.KeepaliveParams{
+			Interval: time.Millisecond,
+			OnError:  func(err error) { t.Errorf("unexpected keepalive error: %v", err) },
+		}, option.WithHeader("X-Keepalive", fmt.Sprint(i)))
+		defer stop()
+	}
+	// Run with -race: the keepalives must not write to the shared options.
+	time.Sleep(50 * time.Millisecond)
+}
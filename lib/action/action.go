@@ -0,0 +1,241 @@
+// Package action lets Go functions be registered as named Kernel actions and run
+// locally with the same semantics as an invocation of a deployed app: a JSON
+// payload in, a JSON output out, a final status with a status reason, and a
+// sequence of log events.
+//
+//	app := action.NewApp("my-app")
+//	action.Register(app, "scrape", func(ctx context.Context, in ScrapeInput) (ScrapeOutput, error) {
+//		action.Logf(ctx, "scraping %s", in.URL)
+//		...
+//	})
+//	inv, err := app.Invoke(ctx, "scrape", `{"url":"https://example.com"}`)
+//
+// Apps can also be served by [github.com/kernel/kernel-go-sdk/lib/kerneltest] so
+// that code using the SDK's Invocations service can be tested offline.
+package action
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnknownAction is returned when invoking an action that is not registered.
+var ErrUnknownAction = errors.New("action: unknown action")
+
+// Handler runs an action with its raw JSON payload and returns its raw JSON
+// output. The payload is nil when the invocation has none.
+type Handler func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error)
+
+// App is a named collection of actions.
+type App struct {
+	// Name of the application
+	Name string
+
+	mu      sync.RWMutex
+	actions map[string]Handler
+}
+
+// NewApp returns an app with no actions.
+func NewApp(name string) *App {
+	return &App{Name: name, actions: map[string]Handler{}}
+}
+
+// Register adds an action whose payload is decoded into In and whose result is
+// encoded as the invocation output. Registering a name twice replaces the
+// earlier action.
+func Register[In, Out any](app *App, name string, fn func(ctx context.Context, in In) (Out, error)) {
+	app.Handle(name, func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		var in In
+		if len(payload) > 0 {
+			if err := json.Unmarshal(payload, &in); err != nil {
+				return nil, fmt.Errorf("invalid payload: %w", err)
+			}
+		}
+		out, err := fn(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(out)
+	})
+}
+
+// Handle adds an action that works directly on raw JSON.
+func (a *App) Handle(name string, h Handler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.actions == nil {
+		a.actions = map[string]Handler{}
+	}
+	a.actions[name] = h
+}
+
+// Actions returns the names of the registered actions in sorted order.
+func (a *App) Actions() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	names := make([]string, 0, len(a.actions))
+	for name := range a.actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Status of an invocation. The values match the API's invocation statuses.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// LogEvent is a log line emitted by an action while it runs.
+type LogEvent struct {
+	Timestamp time.Time
+	Message   string
+}
+
+// Invocation records a single run of an action.
+type Invocation struct {
+	// ID of the invocation
+	ID string
+	// Name of the action invoked
+	ActionName string
+	// Name of the application
+	AppName string
+	// Payload provided to the invocation, as a JSON string.
+	Payload string
+	// Final status of the invocation, either succeeded or failed.
+	Status Status
+	// Why the invocation failed, if it did.
+	StatusReason string
+	// Output produced by the action, rendered as a JSON string.
+	Output     string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// Log events emitted by the action, in order.
+	Logs []LogEvent
+}
+
+// InvokeOption configures a single call to [App.Invoke].
+type InvokeOption func(*invokeConfig)
+
+type invokeConfig struct {
+	id    string
+	onLog func(LogEvent)
+}
+
+// WithInvocationID sets the ID recorded on the invocation instead of generating
+// one.
+func WithInvocationID(id string) InvokeOption {
+	return func(c *invokeConfig) { c.id = id }
+}
+
+// WithLogHandler calls fn with each log event as the action emits it, in
+// addition to recording it on the invocation.
+func WithLogHandler(fn func(LogEvent)) InvokeOption {
+	return func(c *invokeConfig) { c.onLog = fn }
+}
+
+// Invoke runs the named action synchronously. The returned error is only
+// non-nil when the action does not exist or the payload is not valid JSON;
+// failures of the action itself are reported through the invocation's status
+// and status reason, as they would be by the API. A panicking action fails the
+// invocation rather than the caller.
+func (a *App) Invoke(ctx context.Context, actionName string, payload string, opts ...InvokeOption) (*Invocation, error) {
+	a.mu.RLock()
+	h, ok := a.actions[actionName]
+	a.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q in app %q", ErrUnknownAction, actionName, a.Name)
+	}
+	if payload != "" && !json.Valid([]byte(payload)) {
+		return nil, errors.New("action: payload is not valid JSON")
+	}
+
+	cfg := invokeConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.id == "" {
+		cfg.id = NewInvocationID()
+	}
+
+	inv := &Invocation{
+		ID:         cfg.id,
+		ActionName: actionName,
+		AppName:    a.Name,
+		Payload:    payload,
+		StartedAt:  time.Now(),
+	}
+	var mu sync.Mutex
+	sink := func(e LogEvent) {
+		mu.Lock()
+		inv.Logs = append(inv.Logs, e)
+		mu.Unlock()
+		if cfg.onLog != nil {
+			cfg.onLog(e)
+		}
+	}
+
+	var raw json.RawMessage
+	if payload != "" {
+		raw = json.RawMessage(payload)
+	}
+	out, err := run(context.WithValue(ctx, logSinkKey{}, sink), h, raw)
+
+	mu.Lock()
+	defer mu.Unlock()
+	inv.FinishedAt = time.Now()
+	if err != nil {
+		inv.Status = StatusFailed
+		inv.StatusReason = err.Error()
+		return inv, nil
+	}
+	inv.Status = StatusSucceeded
+	inv.Output = string(out)
+	return inv, nil
+}
+
+func run(ctx context.Context, h Handler, payload json.RawMessage) (out json.RawMessage, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return h(ctx, payload)
+}
+
+type logSinkKey struct{}
+
+// Logf emits a log event from within an action. Outside of an invocation it
+// writes to the standard logger.
+func Logf(ctx context.Context, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if sink, ok := ctx.Value(logSinkKey{}).(func(LogEvent)); ok {
+		sink(LogEvent{Timestamp: time.Now(), Message: msg})
+		return
+	}
+	log.Print(msg)
+}
+
+// NewInvocationID returns a random identifier in the same shape as the API's
+// invocation IDs: 24 lowercase letters and digits.
+func NewInvocationID() string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, 24)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
@@ -0,0 +1,72 @@
+package action_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk/lib/action"
+)
+
+type greetInput struct {
+	Name string `json:"name"`
+}
+
+type greetOutput struct {
+	Greeting string `json:"greeting"`
+}
+
+func newGreeter() *action.App {
+	app := action.NewApp("greeter")
+	action.Register(app, "greet", func(ctx context.Context, in greetInput) (greetOutput, error) {
+		if in.Name == "" {
+			return greetOutput{}, errors.New("name is required")
+		}
+		action.Logf(ctx, "greeting %s", in.Name)
+		return greetOutput{Greeting: "hello " + in.Name}, nil
+	})
+	action.Register(app, "explode", func(ctx context.Context, in struct{}) (struct{}, error) {
+		panic("boom")
+	})
+	return app
+}
+
+func TestInvoke(t *testing.T) {
+	app := newGreeter()
+	inv, err := app.Invoke(context.Background(), "greet", `{"name":"ada"}`)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if inv.Status != action.StatusSucceeded || inv.Output != `{"greeting":"hello ada"}` {
+		t.Errorf("unexpected invocation: %+v", inv)
+	}
+	if len(inv.Logs) != 1 || inv.Logs[0].Message != "greeting ada" {
+		t.Errorf("expected one log event, got %+v", inv.Logs)
+	}
+}
+
+func TestInvokeFailures(t *testing.T) {
+	app := newGreeter()
+	cases := map[string]struct {
+		action, payload, reason string
+	}{
+		"error":           {"greet", `{}`, "name is required"},
+		"invalid payload": {"greet", `{"name":1}`, "invalid payload: json: cannot unmarshal number into Go struct field greetInput.name of type string"},
+		"panic":           {"explode", ``, "panic: boom"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			inv, err := app.Invoke(context.Background(), c.action, c.payload)
+			if err != nil {
+				t.Fatalf("err should be nil: %s", err.Error())
+			}
+			if inv.Status != action.StatusFailed || inv.StatusReason != c.reason {
+				t.Errorf("expected failure %q, got %+v", c.reason, inv)
+			}
+		})
+	}
+
+	if _, err := app.Invoke(context.Background(), "missing", ""); !errors.Is(err, action.ErrUnknownAction) {
+		t.Errorf("expected ErrUnknownAction, got %v", err)
+	}
+}
@@ -0,0 +1,556 @@
+// Package cdp is a Chrome DevTools Protocol client for Kernel browser sessions.
+//
+// Dial connects to a session's CdpWsURL with the same HTTP client settings as the
+// SDK client. The URL carries its own credentials, so the API key is not sent:
+//
+//	browser, _ := client.Browsers.NewSession(ctx, kernel.BrowserNewParams{})
+//	conn, err := cdp.Dial(ctx, browser.Browser.CdpWsURL, client.Options...)
+//	if err != nil { ... }
+//	defer conn.Close()
+//
+//	page, err := conn.NewPage(ctx, "about:blank")
+//	loaded := cdp.On[cdp.PageLoadEventFired](page)
+//	defer loaded.Close()
+//	page.Page().Enable(ctx)
+//	page.Page().Navigate(ctx, "https://example.com")
+//	<-loaded.C
+//
+// Commands on the connection itself address the browser target. Page-level
+// commands are sent through a [Session], which multiplexes a target over the
+// same connection using flat session mode. Typed wrappers cover the core
+// domains (Target, Page, Runtime, Network, Input and DOM); any other command can
+// be sent with Call.
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/kernel/kernel-go-sdk/internal/requestconfig"
+	"github.com/kernel/kernel-go-sdk/internal/websocket"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// ErrClosed is returned for calls on a closed connection.
+var ErrClosed = errors.New("cdp: connection closed")
+
+// ErrDetached is returned for calls on a session whose target has been detached
+// or closed.
+var ErrDetached = errors.New("cdp: session detached")
+
+// Error is an error returned by the browser for a command.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Data != "" {
+		return fmt.Sprintf("cdp: %s (%d): %s", e.Message, e.Code, e.Data)
+	}
+	return fmt.Sprintf("cdp: %s (%d)", e.Message, e.Code)
+}
+
+// Caller sends commands. It is implemented by [*Conn] and [*Session].
+type Caller interface {
+	// Call sends method with params, which is encoded as JSON, and decodes the
+	// result into result unless it is nil.
+	Call(ctx context.Context, method string, params any, result any) error
+}
+
+// Subscriber delivers events. It is implemented by [*Conn] and [*Session].
+type Subscriber interface {
+	// Subscribe delivers events named method, or all events if method is empty.
+	Subscribe(method string) *Subscription
+}
+
+// Event is a CDP event with undecoded params.
+type Event struct {
+	Method string
+	// SessionID is the session the event belongs to, or empty for browser-level
+	// events.
+	SessionID string
+	Params    json.RawMessage
+}
+
+type message struct {
+	ID        int64           `json:"id,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *Error          `json:"error,omitempty"`
+}
+
+type pendingCall struct {
+	sessionID string
+	done      chan message
+}
+
+// Conn is a connection to a browser's DevTools endpoint. It is safe for
+// concurrent use.
+type Conn struct {
+	ws *websocket.Conn
+
+	mu       sync.Mutex
+	nextID   int64
+	pending  map[int64]*pendingCall
+	subs     map[*Subscription]struct{}
+	detached map[string]bool
+	err      error
+	done     chan struct{}
+}
+
+// Dial connects to a DevTools websocket URL such as
+// [github.com/kernel/kernel-go-sdk.BrowserNewResponse.CdpWsURL]. The request
+// options supply headers and the HTTP client, so the SDK client's Options can be
+// passed as they are. The URL authenticates the connection by itself, so the
+// Authorization header they set, which holds the organization's API key, is
+// never sent to the browser's host. ctx bounds the handshake only.
+func Dial(ctx context.Context, wsURL string, opts ...option.RequestOption) (*Conn, error) {
+	cfg, err := requestconfig.NewRequestConfig(ctx, http.MethodGet, wsURL, nil, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	req := cfg.Request
+	req.Header.Del("Accept")
+	req.Header.Del("Authorization")
+	var doer websocket.Doer = cfg.HTTPClient
+	if cfg.CustomHTTPDoer != nil {
+		doer = cfg.CustomHTTPDoer
+	}
+	ws, err := websocket.Dial(ctx, doer, req)
+	if err != nil {
+		return nil, fmt.Errorf("cdp: dialing %s: %w", redactURL(req), err)
+	}
+	c := &Conn{
+		ws:       ws,
+		pending:  map[int64]*pendingCall{},
+		subs:     map[*Subscription]struct{}{},
+		detached: map[string]bool{},
+		done:     make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func redactURL(req *http.Request) string {
+	u := *req.URL
+	u.RawQuery = ""
+	return u.String()
+}
+
+// Call sends a browser-level command. See [Caller].
+func (c *Conn) Call(ctx context.Context, method string, params any, result any) error {
+	return c.call(ctx, "", method, params, result)
+}
+
+// Subscribe delivers browser-level and session events named method, or all
+// events if method is empty. See [Subscription].
+func (c *Conn) Subscribe(method string) *Subscription {
+	return c.subscribe(method, "", true)
+}
+
+// Session returns a handle for an attached session ID.
+func (c *Conn) Session(sessionID string) *Session {
+	return &Session{conn: c, id: sessionID}
+}
+
+// Attach attaches to a target in flat mode and returns its session.
+func (c *Conn) Attach(ctx context.Context, targetID string) (*Session, error) {
+	sessionID, err := c.Target().AttachToTarget(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return c.Session(sessionID), nil
+}
+
+// NewPage opens a new page target at url and attaches to it.
+func (c *Conn) NewPage(ctx context.Context, url string) (*Session, error) {
+	targetID, err := c.Target().CreateTarget(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.Attach(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	s.targetID = targetID
+	return s, nil
+}
+
+// Done is closed when the connection is closed, either by [Conn.Close] or
+// because the browser went away.
+func (c *Conn) Done() <-chan struct{} { return c.done }
+
+// Err returns why the connection was closed, or nil while it is open. It is
+// [ErrClosed] after [Conn.Close].
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close closes the connection. Pending calls fail with [ErrClosed] and all
+// subscriptions are closed.
+func (c *Conn) Close() error {
+	c.shutdown(ErrClosed)
+	return c.ws.Close()
+}
+
+func (c *Conn) shutdown(err error) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.err = err
+	pending := c.pending
+	c.pending = nil
+	subs := c.subs
+	c.subs = nil
+	close(c.done)
+	c.mu.Unlock()
+
+	for _, p := range pending {
+		close(p.done)
+	}
+	for s := range subs {
+		s.closeQueue()
+	}
+}
+
+func (c *Conn) call(ctx context.Context, sessionID string, method string, params any, result any) error {
+	var raw json.RawMessage
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("cdp: encoding %s params: %w", method, err)
+		}
+		raw = b
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return err
+	}
+	if sessionID != "" && c.detached[sessionID] {
+		c.mu.Unlock()
+		return ErrDetached
+	}
+	c.nextID++
+	id := c.nextID
+	p := &pendingCall{sessionID: sessionID, done: make(chan message, 1)}
+	c.pending[id] = p
+	c.mu.Unlock()
+
+	b, err := json.Marshal(message{ID: id, Method: method, Params: raw, SessionID: sessionID})
+	if err == nil {
+		err = c.ws.WriteMessage(websocket.OpText, b)
+	}
+	if err != nil {
+		c.forget(id)
+		if connErr := c.Err(); connErr != nil {
+			return connErr
+		}
+		return fmt.Errorf("cdp: sending %s: %w", method, err)
+	}
+
+	select {
+	case msg, ok := <-p.done:
+		if !ok {
+			if sessionID != "" && c.isDetached(sessionID) {
+				return ErrDetached
+			}
+			return c.Err()
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil && len(msg.Result) > 0 {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				return fmt.Errorf("cdp: decoding %s result: %w", method, err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		c.forget(id)
+		return ctx.Err()
+	}
+}
+
+func (c *Conn) forget(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+}
+
+func (c *Conn) isDetached(sessionID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.detached[sessionID]
+}
+
+func (c *Conn) readLoop() {
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			c.ws.Close()
+			c.shutdown(fmt.Errorf("cdp: connection lost: %w", err))
+			return
+		}
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		if msg.ID != 0 {
+			c.mu.Lock()
+			p := c.pending[msg.ID]
+			delete(c.pending, msg.ID)
+			c.mu.Unlock()
+			if p != nil {
+				p.done <- msg
+			}
+			continue
+		}
+		if msg.Method == "Target.detachedFromTarget" {
+			var params struct {
+				SessionID string `json:"sessionId"`
+			}
+			if json.Unmarshal(msg.Params, &params) == nil && params.SessionID != "" {
+				c.detach(params.SessionID)
+			}
+		}
+		c.dispatch(Event{Method: msg.Method, SessionID: msg.SessionID, Params: msg.Params})
+	}
+}
+
+// detach fails the pending calls of a session whose target went away.
+func (c *Conn) detach(sessionID string) {
+	c.mu.Lock()
+	c.detached[sessionID] = true
+	var failed []*pendingCall
+	for id, p := range c.pending {
+		if p.sessionID == sessionID {
+			failed = append(failed, p)
+			delete(c.pending, id)
+		}
+	}
+	var subs []*Subscription
+	for s := range c.subs {
+		if s.sessionID == sessionID && !s.allSessions {
+			subs = append(subs, s)
+			delete(c.subs, s)
+		}
+	}
+	c.mu.Unlock()
+	for _, p := range failed {
+		close(p.done)
+	}
+	for _, s := range subs {
+		s.closeQueue()
+	}
+}
+
+func (c *Conn) dispatch(e Event) {
+	c.mu.Lock()
+	var targets []*Subscription
+	for s := range c.subs {
+		if s.method != "" && s.method != e.Method {
+			continue
+		}
+		if !s.allSessions && s.sessionID != e.SessionID {
+			continue
+		}
+		targets = append(targets, s)
+	}
+	c.mu.Unlock()
+	for _, s := range targets {
+		s.push(e)
+	}
+}
+
+func (c *Conn) subscribe(method string, sessionID string, allSessions bool) *Subscription {
+	ch := make(chan Event)
+	s := &Subscription{C: ch, ch: ch, conn: c, method: method, sessionID: sessionID, allSessions: allSessions, wake: make(chan struct{}, 1), stop: make(chan struct{})}
+	c.mu.Lock()
+	closed := c.err != nil || (sessionID != "" && c.detached[sessionID])
+	if !closed {
+		c.subs[s] = struct{}{}
+	}
+	c.mu.Unlock()
+	go s.deliver()
+	if closed {
+		s.closeQueue()
+	}
+	return s
+}
+
+// Session is a target attached over a [Conn] in flat mode. It is safe for
+// concurrent use.
+type Session struct {
+	conn     *Conn
+	id       string
+	targetID string
+}
+
+// ID returns the CDP session ID.
+func (s *Session) ID() string { return s.id }
+
+// Conn returns the connection the session is multiplexed over.
+func (s *Session) Conn() *Conn { return s.conn }
+
+// Call sends a command to the session's target. See [Caller].
+func (s *Session) Call(ctx context.Context, method string, params any, result any) error {
+	return s.conn.call(ctx, s.id, method, params, result)
+}
+
+// Subscribe delivers the session's events named method, or all of its events if
+// method is empty. The subscription is closed when the session is detached.
+func (s *Session) Subscribe(method string) *Subscription {
+	return s.conn.subscribe(method, s.id, false)
+}
+
+// Close detaches from the target and, for sessions created by [Conn.NewPage],
+// closes the page.
+func (s *Session) Close(ctx context.Context) error {
+	err := s.conn.Target().DetachFromTarget(ctx, s.id)
+	if s.targetID != "" {
+		err = errors.Join(err, s.conn.Target().CloseTarget(ctx, s.targetID))
+	}
+	s.conn.detach(s.id)
+	return err
+}
+
+// Subscription delivers events in order on C. Events are queued without bound,
+// so a slow reader never blocks the connection; close subscriptions that are no
+// longer read. C is closed once the subscription, its session or the connection
+// is closed.
+type Subscription struct {
+	C <-chan Event
+
+	ch          chan Event
+	conn        *Conn
+	method      string
+	sessionID   string
+	allSessions bool
+
+	mu     sync.Mutex
+	queue  []Event
+	closed bool
+	wake   chan struct{}
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// Close stops delivery and closes C. Undelivered events are dropped.
+func (s *Subscription) Close() {
+	s.conn.mu.Lock()
+	if s.conn.subs != nil {
+		delete(s.conn.subs, s)
+	}
+	s.conn.mu.Unlock()
+	s.once.Do(func() { close(s.stop) })
+}
+
+func (s *Subscription) push(e Event) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.queue = append(s.queue, e)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// closeQueue closes C once the queued events have been delivered.
+func (s *Subscription) closeQueue() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Subscription) deliver() {
+	defer close(s.ch)
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return
+			}
+			select {
+			case <-s.wake:
+				continue
+			case <-s.stop:
+				return
+			}
+		}
+		e := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		select {
+		case s.ch <- e:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// EventParams is implemented by the typed event structs of this package. The
+// method must work on the zero value.
+type EventParams interface {
+	EventMethod() string
+}
+
+// TypedSubscription delivers decoded events of type E on C. Events whose params
+// cannot be decoded are skipped.
+type TypedSubscription[E EventParams] struct {
+	C   <-chan E
+	sub *Subscription
+}
+
+// Close stops delivery and closes C.
+func (s *TypedSubscription[E]) Close() { s.sub.Close() }
+
+// On subscribes to the events of type E:
+//
+//	sub := cdp.On[cdp.NetworkResponseReceived](session)
+//	defer sub.Close()
+//	for e := range sub.C { ... }
+func On[E EventParams](src Subscriber) *TypedSubscription[E] {
+	var zero E
+	sub := src.Subscribe(zero.EventMethod())
+	ch := make(chan E)
+	go func() {
+		defer close(ch)
+		for e := range sub.C {
+			var v E
+			if err := json.Unmarshal(e.Params, &v); err != nil {
+				continue
+			}
+			select {
+			case ch <- v:
+			case <-sub.stop:
+				return
+			}
+		}
+	}()
+	return &TypedSubscription[E]{C: ch, sub: sub}
+}
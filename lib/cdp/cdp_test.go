@@ -0,0 +1,212 @@
+package cdp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/lib/cdp"
+	"github.com/kernel/kernel-go-sdk/lib/cdp/cdptest"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+func dial(t *testing.T) (*cdptest.Server, *cdp.Conn) {
+	srv := cdptest.NewServer()
+	t.Cleanup(srv.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := cdp.Dial(ctx, srv.URL(), option.WithAPIKey("My API Key"))
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	t.Cleanup(func() { conn.Close() })
+	return srv, conn
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestDialOmitsAPIKey(t *testing.T) {
+	srv, _ := dial(t)
+	if got := srv.Header().Get("Authorization"); got != "" {
+		t.Errorf("expected the API key not to be sent with the handshake, got %q", got)
+	}
+}
+
+func TestPageSession(t *testing.T) {
+	srv, conn := dial(t)
+	ctx := testContext(t)
+	srv.Handle("Page.enable", func(cdptest.Call) (any, error) { return nil, nil })
+	srv.Handle("Page.navigate", func(call cdptest.Call) (any, error) {
+		go srv.Emit(call.SessionID, "Page.loadEventFired", map[string]any{"timestamp": 1.5})
+		return map[string]any{"frameId": "frame-1"}, nil
+	})
+	srv.Handle("Runtime.evaluate", func(call cdptest.Call) (any, error) {
+		var params cdp.RuntimeEvaluateParams
+		json.Unmarshal(call.Params, &params)
+		if params.Expression == "throw" {
+			return map[string]any{
+				"result":           map[string]any{"type": "object"},
+				"exceptionDetails": map[string]any{"text": "Uncaught", "exception": map[string]any{"type": "object", "description": "Error: boom"}},
+			}, nil
+		}
+		return map[string]any{"result": map[string]any{"type": "number", "value": 2}}, nil
+	})
+
+	page, err := conn.NewPage(ctx, "about:blank")
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if page.ID() != "session-1" {
+		t.Errorf("unexpected session ID %q", page.ID())
+	}
+
+	loaded := cdp.On[cdp.PageLoadEventFired](page)
+	defer loaded.Close()
+	if err := page.Page().Enable(ctx); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	nav, err := page.Page().Navigate(ctx, "https://example.com")
+	if err != nil || nav.FrameID != "frame-1" {
+		t.Fatalf("Navigate() = %+v, %v", nav, err)
+	}
+	select {
+	case e := <-loaded.C:
+		if e.Timestamp != 1.5 {
+			t.Errorf("unexpected event %+v", e)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the load event")
+	}
+
+	var n int
+	if err := page.Runtime().Eval(ctx, "1+1", &n); err != nil || n != 2 {
+		t.Errorf("Eval() = %d, %v", n, err)
+	}
+	var exc *cdp.ExceptionDetails
+	if err := page.Runtime().Eval(ctx, "throw", nil); !errors.As(err, &exc) || err.Error() != "cdp: Error: boom" {
+		t.Errorf("expected the exception as an error, got %v", err)
+	}
+
+	for _, call := range srv.Calls() {
+		if call.Method != "Target.createTarget" && call.Method != "Target.attachToTarget" && call.SessionID != "session-1" {
+			t.Errorf("%s was not sent to the page session", call.Method)
+		}
+	}
+}
+
+func TestCallError(t *testing.T) {
+	_, conn := dial(t)
+	err := conn.Call(testContext(t), "Browser.crash", nil, nil)
+	var cdpErr *cdp.Error
+	if !errors.As(err, &cdpErr) || cdpErr.Code != -32601 {
+		t.Errorf("expected a method not found error, got %v", err)
+	}
+}
+
+func TestSessionMultiplexing(t *testing.T) {
+	srv, conn := dial(t)
+	ctx := testContext(t)
+	a, b := conn.Session("a"), conn.Session("b")
+	subA, subB := a.Subscribe(""), b.Subscribe("")
+	defer subA.Close()
+	defer subB.Close()
+	all := conn.Subscribe("Network.requestWillBeSent")
+	defer all.Close()
+
+	srv.Emit("b", "Network.requestWillBeSent", map[string]any{"requestId": "1"})
+	srv.Emit("a", "Network.requestWillBeSent", map[string]any{"requestId": "2"})
+
+	for _, tc := range []struct {
+		sub  *cdp.Subscription
+		want string
+	}{{subA, "a"}, {subB, "b"}} {
+		select {
+		case e := <-tc.sub.C:
+			if e.SessionID != tc.want {
+				t.Errorf("session %s received an event for %s", tc.want, e.SessionID)
+			}
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for an event")
+		}
+	}
+	for _, want := range []string{"b", "a"} {
+		select {
+		case e := <-all.C:
+			if e.SessionID != want {
+				t.Errorf("expected events in order, got %s want %s", e.SessionID, want)
+			}
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for an event")
+		}
+	}
+}
+
+func TestDetachFailsPendingCalls(t *testing.T) {
+	srv, conn := dial(t)
+	ctx := testContext(t)
+	started := make(chan struct{})
+	srv.Handle("Runtime.evaluate", func(call cdptest.Call) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, nil
+	})
+	page, err := conn.Attach(ctx, "target-1")
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- page.Runtime().Eval(ctx, "new Promise(() => {})", nil) }()
+	<-started
+	srv.Emit("", "Target.detachedFromTarget", map[string]any{"sessionId": page.ID()})
+	if err := <-errc; !errors.Is(err, cdp.ErrDetached) {
+		t.Errorf("expected ErrDetached, got %v", err)
+	}
+	if err := page.Page().Enable(ctx); !errors.Is(err, cdp.ErrDetached) {
+		t.Errorf("expected ErrDetached for a detached session, got %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	srv, conn := dial(t)
+	ctx := testContext(t)
+	srv.Handle("Runtime.evaluate", func(call cdptest.Call) (any, error) {
+		<-ctx.Done()
+		return nil, nil
+	})
+	sub := conn.Subscribe("")
+	errc := make(chan error, 1)
+	go func() { errc <- conn.Session("s").Runtime().Eval(ctx, "1", nil) }()
+	for len(srv.Calls()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	conn.Close()
+	if err := <-errc; !errors.Is(err, cdp.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Error("expected subscriptions to be closed")
+	}
+	<-conn.Done()
+	if err := conn.Call(ctx, "Browser.getVersion", nil, nil); !errors.Is(err, cdp.ErrClosed) {
+		t.Errorf("expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestConnectionLost(t *testing.T) {
+	srv, conn := dial(t)
+	srv.Disconnect()
+	select {
+	case <-conn.Done():
+	case <-testContext(t).Done():
+		t.Fatal("timed out waiting for the connection to close")
+	}
+	if conn.Err() == nil || errors.Is(conn.Err(), cdp.ErrClosed) {
+		t.Errorf("expected a connection lost error, got %v", conn.Err())
+	}
+}
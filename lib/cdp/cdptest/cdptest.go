@@ -0,0 +1,246 @@
+// Package cdptest provides a scripted Chrome DevTools Protocol endpoint for
+// testing code built on [github.com/kernel/kernel-go-sdk/lib/cdp] without a
+// browser:
+//
+//	srv := cdptest.NewServer()
+//	defer srv.Close()
+//	srv.Handle("Runtime.evaluate", func(call cdptest.Call) (any, error) {
+//		return map[string]any{"result": map[string]any{"type": "number", "value": 2}}, nil
+//	})
+//
+//	conn, err := cdp.Dial(ctx, srv.URL())
+//
+// Target.createTarget, Target.attachToTarget, Target.detachFromTarget and
+// Target.closeTarget have default handlers that hand out sequential target and
+// session IDs. Any other method without a handler fails with a "method not
+// found" error.
+package cdptest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/kernel/kernel-go-sdk/internal/websocket"
+	"github.com/kernel/kernel-go-sdk/lib/cdp"
+)
+
+// Call is a command received by the server.
+type Call struct {
+	Method    string
+	SessionID string
+	Params    json.RawMessage
+}
+
+// HandlerFunc answers a command. The result is encoded as JSON; a nil result is
+// sent as an empty object. A [*cdp.Error] is sent as is and any other error is
+// sent with code -32000.
+type HandlerFunc func(call Call) (any, error)
+
+// Server is a local DevTools websocket endpoint.
+type Server struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	handlers map[string]HandlerFunc
+	calls    []Call
+	conns    map[*websocket.Conn]struct{}
+	header   http.Header
+	targets  int
+	sessions int
+}
+
+// NewServer starts a server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		handlers: map[string]HandlerFunc{},
+		conns:    map[*websocket.Conn]struct{}{},
+	}
+	s.handlers["Target.createTarget"] = s.createTarget
+	s.handlers["Target.attachToTarget"] = s.attachToTarget
+	s.handlers["Target.detachFromTarget"] = s.detachFromTarget
+	s.handlers["Target.closeTarget"] = func(Call) (any, error) { return map[string]any{"success": true}, nil }
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// URL returns the ws:// URL to dial.
+func (s *Server) URL() string {
+	return "ws" + strings.TrimPrefix(s.srv.URL, "http") + "/devtools/browser"
+}
+
+// Handle sets the handler for method, replacing any previous one.
+func (s *Server) Handle(method string, fn HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = fn
+}
+
+// Emit sends an event to every connected client. An empty sessionID sends a
+// browser-level event.
+func (s *Server) Emit(sessionID string, method string, params any) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.broadcast(frame{Method: method, SessionID: sessionID, Params: b})
+}
+
+// Calls returns the commands received so far, in order.
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// Header returns the headers of the most recent websocket handshake.
+func (s *Server) Header() http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.header.Clone()
+}
+
+// Disconnect drops every client connection, as a browser going away would.
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = map[*websocket.Conn]struct{}{}
+	s.mu.Unlock()
+	for c := range conns {
+		c.Close()
+	}
+}
+
+// Close disconnects every client and shuts the server down.
+func (s *Server) Close() {
+	s.Disconnect()
+	s.srv.Close()
+}
+
+type frame struct {
+	ID        int64           `json:"id,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *cdp.Error      `json:"error,omitempty"`
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	ws, err := websocket.Accept(w, r)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.header = r.Header.Clone()
+	s.conns[ws] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, ws)
+		s.mu.Unlock()
+		ws.Close()
+	}()
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req frame
+		if err := json.Unmarshal(data, &req); err != nil {
+			return
+		}
+		call := Call{Method: req.Method, SessionID: req.SessionID, Params: req.Params}
+		s.mu.Lock()
+		s.calls = append(s.calls, call)
+		fn := s.handlers[req.Method]
+		s.mu.Unlock()
+		// Handlers run concurrently so one that blocks does not hold up the rest,
+		// as in a browser.
+		go func() {
+			res := frame{ID: req.ID, SessionID: req.SessionID}
+			res.Result, res.Error = reply(fn, call)
+			b, _ := json.Marshal(res)
+			ws.WriteMessage(websocket.OpText, b)
+		}()
+	}
+}
+
+func reply(fn HandlerFunc, call Call) (json.RawMessage, *cdp.Error) {
+	if fn == nil {
+		return nil, &cdp.Error{Code: -32601, Message: fmt.Sprintf("'%s' wasn't found", call.Method)}
+	}
+	v, err := fn(call)
+	if err != nil {
+		var cdpErr *cdp.Error
+		if errors.As(err, &cdpErr) {
+			return nil, cdpErr
+		}
+		return nil, &cdp.Error{Code: -32000, Message: err.Error()}
+	}
+	if v == nil {
+		return json.RawMessage("{}"), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, &cdp.Error{Code: -32603, Message: err.Error()}
+	}
+	return b, nil
+}
+
+func (s *Server) broadcast(f frame) error {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	conns := make([]*websocket.Conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	var errs []error
+	for _, c := range conns {
+		errs = append(errs, c.WriteMessage(websocket.OpText, b))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Server) createTarget(Call) (any, error) {
+	s.mu.Lock()
+	s.targets++
+	id := fmt.Sprintf("target-%d", s.targets)
+	s.mu.Unlock()
+	return map[string]any{"targetId": id}, nil
+}
+
+func (s *Server) attachToTarget(call Call) (any, error) {
+	var params struct {
+		TargetID string `json:"targetId"`
+	}
+	json.Unmarshal(call.Params, &params)
+	if params.TargetID == "" {
+		return nil, &cdp.Error{Code: -32602, Message: "Invalid parameters", Data: "targetId: string value expected"}
+	}
+	s.mu.Lock()
+	s.sessions++
+	id := fmt.Sprintf("session-%d", s.sessions)
+	s.mu.Unlock()
+	return map[string]any{"sessionId": id}, nil
+}
+
+func (s *Server) detachFromTarget(call Call) (any, error) {
+	var params struct {
+		SessionID string `json:"sessionId"`
+	}
+	json.Unmarshal(call.Params, &params)
+	if err := s.Emit("", "Target.detachedFromTarget", map[string]any{"sessionId": params.SessionID}); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,462 @@
+package cdp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Target returns the Target domain, which is always browser-level.
+func (c *Conn) Target() Target { return Target{c} }
+
+// Target returns the Target domain of the connection the session belongs to.
+func (s *Session) Target() Target { return Target{s.conn} }
+
+// Page returns the session's Page domain.
+func (s *Session) Page() Page { return Page{s} }
+
+// Runtime returns the session's Runtime domain.
+func (s *Session) Runtime() Runtime { return Runtime{s} }
+
+// Network returns the session's Network domain.
+func (s *Session) Network() Network { return Network{s} }
+
+// Input returns the session's Input domain.
+func (s *Session) Input() Input { return Input{s} }
+
+// DOM returns the session's DOM domain.
+func (s *Session) DOM() DOM { return DOM{s} }
+
+// Target discovers, creates and attaches to targets.
+type Target struct{ c Caller }
+
+// TargetInfo describes a target.
+type TargetInfo struct {
+	TargetID string `json:"targetId"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Attached bool   `json:"attached"`
+}
+
+// CreateTarget opens a new page at url and returns its target ID.
+func (d Target) CreateTarget(ctx context.Context, url string) (string, error) {
+	var res struct {
+		TargetID string `json:"targetId"`
+	}
+	err := d.c.Call(ctx, "Target.createTarget", map[string]any{"url": url}, &res)
+	return res.TargetID, err
+}
+
+// AttachToTarget attaches to a target in flat mode and returns the session ID.
+func (d Target) AttachToTarget(ctx context.Context, targetID string) (string, error) {
+	var res struct {
+		SessionID string `json:"sessionId"`
+	}
+	err := d.c.Call(ctx, "Target.attachToTarget", map[string]any{"targetId": targetID, "flatten": true}, &res)
+	return res.SessionID, err
+}
+
+// DetachFromTarget detaches a session.
+func (d Target) DetachFromTarget(ctx context.Context, sessionID string) error {
+	return d.c.Call(ctx, "Target.detachFromTarget", map[string]any{"sessionId": sessionID}, nil)
+}
+
+// CloseTarget closes a target.
+func (d Target) CloseTarget(ctx context.Context, targetID string) error {
+	return d.c.Call(ctx, "Target.closeTarget", map[string]any{"targetId": targetID}, nil)
+}
+
+// GetTargets lists the browser's targets.
+func (d Target) GetTargets(ctx context.Context) ([]TargetInfo, error) {
+	var res struct {
+		TargetInfos []TargetInfo `json:"targetInfos"`
+	}
+	err := d.c.Call(ctx, "Target.getTargets", nil, &res)
+	return res.TargetInfos, err
+}
+
+// SetDiscoverTargets turns target creation and destruction events on or off.
+func (d Target) SetDiscoverTargets(ctx context.Context, discover bool) error {
+	return d.c.Call(ctx, "Target.setDiscoverTargets", map[string]any{"discover": discover}, nil)
+}
+
+// TargetTargetCreated is sent when a target is created, if discovery is on.
+type TargetTargetCreated struct {
+	TargetInfo TargetInfo `json:"targetInfo"`
+}
+
+func (TargetTargetCreated) EventMethod() string { return "Target.targetCreated" }
+
+// TargetTargetDestroyed is sent when a target is destroyed, if discovery is on.
+type TargetTargetDestroyed struct {
+	TargetID string `json:"targetId"`
+}
+
+func (TargetTargetDestroyed) EventMethod() string { return "Target.targetDestroyed" }
+
+// TargetAttachedToTarget is sent when a session is attached.
+type TargetAttachedToTarget struct {
+	SessionID  string     `json:"sessionId"`
+	TargetInfo TargetInfo `json:"targetInfo"`
+}
+
+func (TargetAttachedToTarget) EventMethod() string { return "Target.attachedToTarget" }
+
+// TargetDetachedFromTarget is sent when a session is detached.
+type TargetDetachedFromTarget struct {
+	SessionID string `json:"sessionId"`
+	TargetID  string `json:"targetId,omitempty"`
+}
+
+func (TargetDetachedFromTarget) EventMethod() string { return "Target.detachedFromTarget" }
+
+// Page controls navigation and rendering.
+type Page struct{ c Caller }
+
+// Enable turns on Page events.
+func (d Page) Enable(ctx context.Context) error {
+	return d.c.Call(ctx, "Page.enable", nil, nil)
+}
+
+// PageNavigateResult is the result of [Page.Navigate].
+type PageNavigateResult struct {
+	FrameID   string `json:"frameId"`
+	LoaderID  string `json:"loaderId,omitempty"`
+	ErrorText string `json:"errorText,omitempty"`
+}
+
+// Navigate navigates the page to url. A navigation that fails in the browser,
+// such as a DNS error, is reported as an error.
+func (d Page) Navigate(ctx context.Context, url string) (*PageNavigateResult, error) {
+	var res PageNavigateResult
+	if err := d.c.Call(ctx, "Page.navigate", map[string]any{"url": url}, &res); err != nil {
+		return nil, err
+	}
+	if res.ErrorText != "" {
+		return &res, fmt.Errorf("cdp: navigating to %s: %s", url, res.ErrorText)
+	}
+	return &res, nil
+}
+
+// Reload reloads the page.
+func (d Page) Reload(ctx context.Context, ignoreCache bool) error {
+	return d.c.Call(ctx, "Page.reload", map[string]any{"ignoreCache": ignoreCache}, nil)
+}
+
+// PageCaptureScreenshotParams are the options of [Page.CaptureScreenshot].
+type PageCaptureScreenshotParams struct {
+	// Format is "png" (the default), "jpeg" or "webp".
+	Format string `json:"format,omitempty"`
+	// Quality is the compression quality for jpeg and webp, from 0 to 100.
+	Quality int `json:"quality,omitempty"`
+	// CaptureBeyondViewport captures the whole page instead of the viewport.
+	CaptureBeyondViewport bool `json:"captureBeyondViewport,omitempty"`
+}
+
+// CaptureScreenshot captures the page and returns the encoded image.
+func (d Page) CaptureScreenshot(ctx context.Context, params PageCaptureScreenshotParams) ([]byte, error) {
+	var res struct {
+		Data string `json:"data"`
+	}
+	if err := d.c.Call(ctx, "Page.captureScreenshot", params, &res); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(res.Data)
+}
+
+// PageLoadEventFired is sent when the page's load event fires.
+type PageLoadEventFired struct {
+	Timestamp float64 `json:"timestamp"`
+}
+
+func (PageLoadEventFired) EventMethod() string { return "Page.loadEventFired" }
+
+// PageDomContentEventFired is sent when the page's DOMContentLoaded event fires.
+type PageDomContentEventFired struct {
+	Timestamp float64 `json:"timestamp"`
+}
+
+func (PageDomContentEventFired) EventMethod() string { return "Page.domContentEventFired" }
+
+// PageFrame is a frame in the page.
+type PageFrame struct {
+	ID       string `json:"id"`
+	ParentID string `json:"parentId,omitempty"`
+	URL      string `json:"url"`
+}
+
+// PageFrameNavigated is sent when a frame has navigated.
+type PageFrameNavigated struct {
+	Frame PageFrame `json:"frame"`
+}
+
+func (PageFrameNavigated) EventMethod() string { return "Page.frameNavigated" }
+
+// Runtime evaluates JavaScript.
+type Runtime struct{ c Caller }
+
+// Enable turns on Runtime events.
+func (d Runtime) Enable(ctx context.Context) error {
+	return d.c.Call(ctx, "Runtime.enable", nil, nil)
+}
+
+// RemoteObject is a JavaScript value.
+type RemoteObject struct {
+	Type        string          `json:"type"`
+	Subtype     string          `json:"subtype,omitempty"`
+	ClassName   string          `json:"className,omitempty"`
+	Value       json.RawMessage `json:"value,omitempty"`
+	Description string          `json:"description,omitempty"`
+	ObjectID    string          `json:"objectId,omitempty"`
+}
+
+// ExceptionDetails describes an exception thrown by evaluated code.
+type ExceptionDetails struct {
+	Text         string        `json:"text"`
+	LineNumber   int           `json:"lineNumber"`
+	ColumnNumber int           `json:"columnNumber"`
+	Exception    *RemoteObject `json:"exception,omitempty"`
+}
+
+func (e *ExceptionDetails) Error() string {
+	if e.Exception != nil && e.Exception.Description != "" {
+		return "cdp: " + e.Exception.Description
+	}
+	return "cdp: " + e.Text
+}
+
+// RuntimeEvaluateParams are the parameters of [Runtime.Evaluate].
+type RuntimeEvaluateParams struct {
+	Expression    string `json:"expression"`
+	ReturnByValue bool   `json:"returnByValue,omitempty"`
+	AwaitPromise  bool   `json:"awaitPromise,omitempty"`
+	UserGesture   bool   `json:"userGesture,omitempty"`
+}
+
+// RuntimeEvaluateResult is the result of [Runtime.Evaluate].
+type RuntimeEvaluateResult struct {
+	Result           RemoteObject      `json:"result"`
+	ExceptionDetails *ExceptionDetails `json:"exceptionDetails,omitempty"`
+}
+
+// Evaluate evaluates an expression in the page.
+func (d Runtime) Evaluate(ctx context.Context, params RuntimeEvaluateParams) (*RuntimeEvaluateResult, error) {
+	var res RuntimeEvaluateResult
+	if err := d.c.Call(ctx, "Runtime.evaluate", params, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Eval evaluates expression, awaiting it if it is a promise, and decodes its
+// value into result unless it is nil. A thrown exception is returned as a
+// [*ExceptionDetails].
+func (d Runtime) Eval(ctx context.Context, expression string, result any) error {
+	res, err := d.Evaluate(ctx, RuntimeEvaluateParams{Expression: expression, ReturnByValue: true, AwaitPromise: true})
+	if err != nil {
+		return err
+	}
+	if res.ExceptionDetails != nil {
+		return res.ExceptionDetails
+	}
+	if result == nil || len(res.Result.Value) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(res.Result.Value, result); err != nil {
+		return fmt.Errorf("cdp: decoding evaluation result: %w", err)
+	}
+	return nil
+}
+
+// RuntimeConsoleAPICalled is sent when the page calls a console method.
+type RuntimeConsoleAPICalled struct {
+	Type      string         `json:"type"`
+	Args      []RemoteObject `json:"args"`
+	Timestamp float64        `json:"timestamp"`
+}
+
+func (RuntimeConsoleAPICalled) EventMethod() string { return "Runtime.consoleAPICalled" }
+
+// RuntimeExceptionThrown is sent when the page throws an uncaught exception.
+type RuntimeExceptionThrown struct {
+	Timestamp        float64          `json:"timestamp"`
+	ExceptionDetails ExceptionDetails `json:"exceptionDetails"`
+}
+
+func (RuntimeExceptionThrown) EventMethod() string { return "Runtime.exceptionThrown" }
+
+// Network observes and modifies network traffic.
+type Network struct{ c Caller }
+
+// Enable turns on Network events.
+func (d Network) Enable(ctx context.Context) error {
+	return d.c.Call(ctx, "Network.enable", nil, nil)
+}
+
+// Disable turns off Network events.
+func (d Network) Disable(ctx context.Context) error {
+	return d.c.Call(ctx, "Network.disable", nil, nil)
+}
+
+// SetExtraHTTPHeaders sends headers with every request of the page.
+func (d Network) SetExtraHTTPHeaders(ctx context.Context, headers map[string]string) error {
+	return d.c.Call(ctx, "Network.setExtraHTTPHeaders", map[string]any{"headers": headers}, nil)
+}
+
+// NetworkRequest is an HTTP request.
+type NetworkRequest struct {
+	URL     string         `json:"url"`
+	Method  string         `json:"method"`
+	Headers map[string]any `json:"headers"`
+}
+
+// NetworkResponse is an HTTP response.
+type NetworkResponse struct {
+	URL        string         `json:"url"`
+	Status     int            `json:"status"`
+	StatusText string         `json:"statusText"`
+	Headers    map[string]any `json:"headers"`
+	MimeType   string         `json:"mimeType"`
+}
+
+// NetworkRequestWillBeSent is sent before a request is sent.
+type NetworkRequestWillBeSent struct {
+	RequestID string         `json:"requestId"`
+	FrameID   string         `json:"frameId,omitempty"`
+	Type      string         `json:"type,omitempty"`
+	Request   NetworkRequest `json:"request"`
+	Timestamp float64        `json:"timestamp"`
+}
+
+func (NetworkRequestWillBeSent) EventMethod() string { return "Network.requestWillBeSent" }
+
+// NetworkResponseReceived is sent when response headers are received.
+type NetworkResponseReceived struct {
+	RequestID string          `json:"requestId"`
+	FrameID   string          `json:"frameId,omitempty"`
+	Type      string          `json:"type,omitempty"`
+	Response  NetworkResponse `json:"response"`
+	Timestamp float64         `json:"timestamp"`
+}
+
+func (NetworkResponseReceived) EventMethod() string { return "Network.responseReceived" }
+
+// NetworkLoadingFinished is sent when a response body has been received.
+type NetworkLoadingFinished struct {
+	RequestID         string  `json:"requestId"`
+	EncodedDataLength float64 `json:"encodedDataLength"`
+	Timestamp         float64 `json:"timestamp"`
+}
+
+func (NetworkLoadingFinished) EventMethod() string { return "Network.loadingFinished" }
+
+// NetworkLoadingFailed is sent when a request fails.
+type NetworkLoadingFailed struct {
+	RequestID string  `json:"requestId"`
+	ErrorText string  `json:"errorText"`
+	Canceled  bool    `json:"canceled,omitempty"`
+	Timestamp float64 `json:"timestamp"`
+}
+
+func (NetworkLoadingFailed) EventMethod() string { return "Network.loadingFailed" }
+
+// Input simulates user input.
+type Input struct{ c Caller }
+
+// InputMouseEventParams are the parameters of [Input.DispatchMouseEvent].
+type InputMouseEventParams struct {
+	// Type is "mousePressed", "mouseReleased", "mouseMoved" or "mouseWheel".
+	Type string  `json:"type"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	// Button is "none", "left", "middle" or "right".
+	Button     string  `json:"button,omitempty"`
+	ClickCount int     `json:"clickCount,omitempty"`
+	DeltaX     float64 `json:"deltaX,omitempty"`
+	DeltaY     float64 `json:"deltaY,omitempty"`
+	Modifiers  int     `json:"modifiers,omitempty"`
+}
+
+// DispatchMouseEvent dispatches a mouse event to the page.
+func (d Input) DispatchMouseEvent(ctx context.Context, params InputMouseEventParams) error {
+	return d.c.Call(ctx, "Input.dispatchMouseEvent", params, nil)
+}
+
+// InputKeyEventParams are the parameters of [Input.DispatchKeyEvent].
+type InputKeyEventParams struct {
+	// Type is "keyDown", "keyUp", "rawKeyDown" or "char".
+	Type      string `json:"type"`
+	Key       string `json:"key,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Modifiers int    `json:"modifiers,omitempty"`
+}
+
+// DispatchKeyEvent dispatches a key event to the page.
+func (d Input) DispatchKeyEvent(ctx context.Context, params InputKeyEventParams) error {
+	return d.c.Call(ctx, "Input.dispatchKeyEvent", params, nil)
+}
+
+// InsertText inserts text as if it was typed or pasted.
+func (d Input) InsertText(ctx context.Context, text string) error {
+	return d.c.Call(ctx, "Input.insertText", map[string]any{"text": text}, nil)
+}
+
+// DOM reads the page's document.
+type DOM struct{ c Caller }
+
+// Node is a DOM node.
+type Node struct {
+	NodeID         int    `json:"nodeId"`
+	BackendNodeID  int    `json:"backendNodeId"`
+	NodeType       int    `json:"nodeType"`
+	NodeName       string `json:"nodeName"`
+	LocalName      string `json:"localName"`
+	NodeValue      string `json:"nodeValue"`
+	ChildNodeCount int    `json:"childNodeCount,omitempty"`
+	Children       []Node `json:"children,omitempty"`
+	// Attributes alternates attribute names and values.
+	Attributes  []string `json:"attributes,omitempty"`
+	DocumentURL string   `json:"documentURL,omitempty"`
+}
+
+// GetDocument returns the document node.
+func (d DOM) GetDocument(ctx context.Context) (*Node, error) {
+	var res struct {
+		Root Node `json:"root"`
+	}
+	if err := d.c.Call(ctx, "DOM.getDocument", nil, &res); err != nil {
+		return nil, err
+	}
+	return &res.Root, nil
+}
+
+// QuerySelector returns the first node below nodeID matching selector, or 0 if
+// none matches.
+func (d DOM) QuerySelector(ctx context.Context, nodeID int, selector string) (int, error) {
+	var res struct {
+		NodeID int `json:"nodeId"`
+	}
+	err := d.c.Call(ctx, "DOM.querySelector", map[string]any{"nodeId": nodeID, "selector": selector}, &res)
+	return res.NodeID, err
+}
+
+// QuerySelectorAll returns the nodes below nodeID matching selector.
+func (d DOM) QuerySelectorAll(ctx context.Context, nodeID int, selector string) ([]int, error) {
+	var res struct {
+		NodeIDs []int `json:"nodeIds"`
+	}
+	err := d.c.Call(ctx, "DOM.querySelectorAll", map[string]any{"nodeId": nodeID, "selector": selector}, &res)
+	return res.NodeIDs, err
+}
+
+// GetOuterHTML returns the HTML of a node.
+func (d DOM) GetOuterHTML(ctx context.Context, nodeID int) (string, error) {
+	var res struct {
+		OuterHTML string `json:"outerHTML"`
+	}
+	err := d.c.Call(ctx, "DOM.getOuterHTML", map[string]any{"nodeId": nodeID}, &res)
+	return res.OuterHTML, err
+}
@@ -0,0 +1,416 @@
+// Package kerneltest provides an in-process fake of the Kernel API for tests.
+//
+// The fake serves the invocation endpoints (create, get, update, follow and
+// delete browsers) and the app listing on top of apps built with
+// [github.com/kernel/kernel-go-sdk/lib/action], so code that calls
+// Invocations.New and Invocations.FollowStreaming can be exercised end to end
+// without network access:
+//
+//	app := action.NewApp("my-app")
+//	action.Register(app, "greet", greet)
+//
+//	srv := kerneltest.NewServer()
+//	defer srv.Close()
+//	srv.Deploy(app, "1")
+//
+//	client := srv.Client()
+//	res, err := client.Invocations.New(ctx, kernel.InvocationNewParams{...})
+package kerneltest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/lib/action"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// Server is a fake Kernel API backed by locally registered apps.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	apps        map[appKey]*deployedApp
+	invocations map[string]*invocation
+}
+
+type appKey struct{ name, version string }
+
+type deployedApp struct {
+	id      string
+	version string
+	app     *action.App
+}
+
+// NewServer starts a fake server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		apps:        map[appKey]*deployedApp{},
+		invocations: map[string]*invocation{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apps", s.listApps)
+	mux.HandleFunc("/invocations", s.newInvocation)
+	mux.HandleFunc("/invocations/", s.invocationRoutes)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Client returns an SDK client that talks to the fake server. Retries are
+// disabled so failures surface immediately; opts are applied last.
+func (s *Server) Client(opts ...option.RequestOption) kernel.Client {
+	return kernel.NewClient(append([]option.RequestOption{
+		option.WithBaseURL(s.URL),
+		option.WithAPIKey("kerneltest"),
+		option.WithMaxRetries(0),
+	}, opts...)...)
+}
+
+// Deploy makes app invocable under the given version label.
+func (s *Server) Deploy(app *action.App, version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apps[appKey{app.Name, version}] = &deployedApp{
+		id:      action.NewInvocationID(),
+		version: version,
+		app:     app,
+	}
+}
+
+// Invocation returns the recorded state of an invocation and whether it exists.
+func (s *Server) Invocation(id string) (action.Invocation, bool) {
+	s.mu.Lock()
+	inv, ok := s.invocations[id]
+	s.mu.Unlock()
+	if !ok {
+		return action.Invocation{}, false
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	return inv.snapshot(), true
+}
+
+// BrowsersDeleted reports how many times the browsers of an invocation were
+// deleted through the API.
+func (s *Server) BrowsersDeleted(id string) int {
+	s.mu.Lock()
+	inv, ok := s.invocations[id]
+	s.mu.Unlock()
+	if !ok {
+		return 0
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	return inv.browsersDeleted
+}
+
+type invocation struct {
+	mu              sync.Mutex
+	changed         chan struct{}
+	state           action.Invocation
+	version         string
+	events          []map[string]any
+	cancel          context.CancelFunc
+	browsersDeleted int
+}
+
+func (inv *invocation) snapshot() action.Invocation {
+	state := inv.state
+	state.Logs = append([]action.LogEvent(nil), inv.state.Logs...)
+	return state
+}
+
+// publish appends an event and wakes any followers. The caller holds inv.mu.
+func (inv *invocation) publish(event map[string]any) {
+	inv.events = append(inv.events, event)
+	close(inv.changed)
+	inv.changed = make(chan struct{})
+}
+
+func (inv *invocation) publishState() {
+	inv.publish(map[string]any{
+		"event":      "invocation_state",
+		"invocation": inv.json(),
+		"timestamp":  time.Now(),
+	})
+}
+
+func (inv *invocation) json() map[string]any {
+	out := map[string]any{
+		"id":          inv.state.ID,
+		"action_name": inv.state.ActionName,
+		"app_name":    inv.state.AppName,
+		"version":     inv.version,
+		"status":      inv.state.Status,
+		"started_at":  inv.state.StartedAt,
+		"payload":     inv.state.Payload,
+	}
+	if !inv.state.FinishedAt.IsZero() {
+		out["finished_at"] = inv.state.FinishedAt
+	}
+	if inv.state.Output != "" {
+		out["output"] = inv.state.Output
+	}
+	if inv.state.StatusReason != "" {
+		out["status_reason"] = inv.state.StatusReason
+	}
+	return out
+}
+
+func (inv *invocation) terminal() bool {
+	return inv.state.Status == action.StatusSucceeded || inv.state.Status == action.StatusFailed
+}
+
+func (s *Server) listApps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", r.Method+" is not supported")
+		return
+	}
+	name, version := r.URL.Query().Get("app_name"), r.URL.Query().Get("version")
+
+	s.mu.Lock()
+	items := []map[string]any{}
+	for key, d := range s.apps {
+		if (name != "" && key.name != name) || (version != "" && key.version != version) {
+			continue
+		}
+		actions := []map[string]any{}
+		for _, a := range d.app.Actions() {
+			actions = append(actions, map[string]any{"name": a})
+		}
+		items = append(items, map[string]any{
+			"id":         d.id,
+			"app_name":   key.name,
+			"version":    key.version,
+			"deployment": d.id,
+			"region":     "aws.us-east-1a",
+			"env_vars":   map[string]string{},
+			"actions":    actions,
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		return fmt.Sprint(items[i]["app_name"], items[i]["version"]) < fmt.Sprint(items[j]["app_name"], items[j]["version"])
+	})
+	w.Header().Set("X-Next-Offset", "0")
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) newInvocation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", r.Method+" is not supported")
+		return
+	}
+	var body struct {
+		ActionName string `json:"action_name"`
+		AppName    string `json:"app_name"`
+		Version    string `json:"version"`
+		Payload    string `json:"payload"`
+		Async      bool   `json:"async"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	d, ok := s.apps[appKey{body.AppName, body.Version}]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("app %q version %q not found", body.AppName, body.Version))
+		return
+	}
+	if !contains(d.app.Actions(), body.ActionName) {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("action %q not found", body.ActionName))
+		return
+	}
+	if body.Payload != "" && !json.Valid([]byte(body.Payload)) {
+		writeError(w, http.StatusBadRequest, "bad_request", "payload is not valid JSON")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	inv := &invocation{
+		changed: make(chan struct{}),
+		version: body.Version,
+		cancel:  cancel,
+		state: action.Invocation{
+			ID:         action.NewInvocationID(),
+			ActionName: body.ActionName,
+			AppName:    body.AppName,
+			Payload:    body.Payload,
+			Status:     action.StatusQueued,
+			StartedAt:  time.Now(),
+		},
+	}
+	s.mu.Lock()
+	s.invocations[inv.state.ID] = inv
+	s.mu.Unlock()
+
+	run := func() {
+		defer cancel()
+		inv.mu.Lock()
+		if inv.terminal() {
+			inv.mu.Unlock()
+			return
+		}
+		inv.state.Status = action.StatusRunning
+		inv.publishState()
+		inv.mu.Unlock()
+
+		res, _ := d.app.Invoke(ctx, body.ActionName, body.Payload,
+			action.WithInvocationID(inv.state.ID),
+			action.WithLogHandler(func(e action.LogEvent) {
+				inv.mu.Lock()
+				defer inv.mu.Unlock()
+				inv.state.Logs = append(inv.state.Logs, e)
+				inv.publish(map[string]any{"event": "log", "message": e.Message, "timestamp": e.Timestamp})
+			}))
+
+		inv.mu.Lock()
+		defer inv.mu.Unlock()
+		// The invocation may have been cancelled through an update while the
+		// action was running; the first terminal state wins.
+		if inv.terminal() {
+			return
+		}
+		inv.state.Status = res.Status
+		inv.state.Output = res.Output
+		inv.state.StatusReason = res.StatusReason
+		inv.state.FinishedAt = res.FinishedAt
+		inv.publishState()
+	}
+
+	if body.Async {
+		inv.mu.Lock()
+		inv.publishState()
+		resp := inv.json()
+		inv.mu.Unlock()
+		go run()
+		writeJSON(w, http.StatusAccepted, resp)
+		return
+	}
+	run()
+	inv.mu.Lock()
+	resp := inv.json()
+	inv.mu.Unlock()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) invocationRoutes(w http.ResponseWriter, r *http.Request) {
+	id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/invocations/"), "/")
+	s.mu.Lock()
+	inv, ok := s.invocations[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("invocation %q not found", id))
+		return
+	}
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		inv.mu.Lock()
+		resp := inv.json()
+		inv.mu.Unlock()
+		writeJSON(w, http.StatusOK, resp)
+	case rest == "" && r.Method == http.MethodPatch:
+		s.updateInvocation(w, r, inv)
+	case rest == "events" && r.Method == http.MethodGet:
+		s.followInvocation(w, r, inv)
+	case rest == "browsers" && r.Method == http.MethodDelete:
+		inv.mu.Lock()
+		inv.browsersDeleted++
+		inv.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, "not_found", r.Method+" "+r.URL.Path+" is not supported")
+	}
+}
+
+func (s *Server) updateInvocation(w http.ResponseWriter, r *http.Request, inv *invocation) {
+	var body struct {
+		Status string  `json:"status"`
+		Output *string `json:"output"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	status := action.Status(body.Status)
+	if status != action.StatusSucceeded && status != action.StatusFailed {
+		writeError(w, http.StatusBadRequest, "bad_request", "status must be succeeded or failed")
+		return
+	}
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if !inv.terminal() {
+		inv.state.Status = status
+		inv.state.FinishedAt = time.Now()
+		if body.Output != nil {
+			inv.state.Output = *body.Output
+		}
+		inv.cancel()
+		inv.publishState()
+	}
+	writeJSON(w, http.StatusOK, inv.json())
+}
+
+func (s *Server) followInvocation(w http.ResponseWriter, r *http.Request, inv *invocation) {
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+
+	for next := 0; ; {
+		inv.mu.Lock()
+		events := inv.events[next:]
+		next = len(inv.events)
+		done := inv.terminal()
+		changed := inv.changed
+		inv.mu.Unlock()
+
+		for _, event := range events {
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event["event"], data)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, map[string]string{"code": code, "message": message})
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
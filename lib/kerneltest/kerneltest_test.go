@@ -0,0 +1,135 @@
+package kerneltest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/lib/action"
+	"github.com/kernel/kernel-go-sdk/lib/kerneltest"
+)
+
+func newServer(t *testing.T, release <-chan struct{}) *kerneltest.Server {
+	app := action.NewApp("greeter")
+	action.Register(app, "greet", func(ctx context.Context, in struct{ Name string }) (string, error) {
+		action.Logf(ctx, "greeting %s", in.Name)
+		if release != nil {
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+		return "hello " + in.Name, nil
+	})
+	srv := kerneltest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.Deploy(app, "1")
+	return srv
+}
+
+func TestInvocationsNew(t *testing.T) {
+	client := newServer(t, nil).Client()
+	res, err := client.Invocations.New(context.Background(), kernel.InvocationNewParams{
+		AppName:    "greeter",
+		ActionName: "greet",
+		Version:    "1",
+		Payload:    kernel.String(`{"Name":"ada"}`),
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.Status != kernel.InvocationNewResponseStatusSucceeded || res.Output != `"hello ada"` {
+		t.Errorf("unexpected response: %s", res.RawJSON())
+	}
+
+	apps, err := client.Apps.List(context.Background(), kernel.AppListParams{})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if len(apps.Items) != 1 || len(apps.Items[0].Actions) != 1 || apps.Items[0].Actions[0].Name != "greet" {
+		t.Errorf("unexpected apps: %s", apps.RawJSON())
+	}
+}
+
+func TestInvocationsFollowStreaming(t *testing.T) {
+	release := make(chan struct{})
+	srv := newServer(t, release)
+	client := srv.Client()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := client.Invocations.New(ctx, kernel.InvocationNewParams{
+		AppName:    "greeter",
+		ActionName: "greet",
+		Version:    "1",
+		Payload:    kernel.String(`{"Name":"ada"}`),
+		Async:      kernel.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.Status != kernel.InvocationNewResponseStatusQueued {
+		t.Fatalf("expected a queued invocation, got %s", res.Status)
+	}
+
+	stream := client.Invocations.FollowStreaming(ctx, res.ID, kernel.InvocationFollowParams{})
+	defer stream.Close()
+	var logs []string
+	var final kernel.InvocationStateEventInvocation
+	for stream.Next() {
+		switch event := stream.Current().AsAny().(type) {
+		case kernel.InvocationStateEvent:
+			if event.Invocation.Status == "running" && len(logs) == 0 {
+				continue
+			}
+			final = event.Invocation
+		case kernel.LogEvent:
+			logs = append(logs, event.Message)
+			close(release)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if final.Status != "succeeded" || final.Output != `"hello ada"` {
+		t.Errorf("unexpected final state: %+v", final)
+	}
+	if len(logs) != 1 || logs[0] != "greeting ada" {
+		t.Errorf("unexpected logs: %v", logs)
+	}
+}
+
+func TestInvocationsUpdateCancels(t *testing.T) {
+	srv := newServer(t, make(chan struct{}))
+	client := srv.Client()
+	ctx := context.Background()
+
+	res, err := client.Invocations.New(ctx, kernel.InvocationNewParams{
+		AppName:    "greeter",
+		ActionName: "greet",
+		Version:    "1",
+		Async:      kernel.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if _, err := client.Invocations.Update(ctx, res.ID, kernel.InvocationUpdateParams{
+		Status: kernel.InvocationUpdateParamsStatusFailed,
+		Output: kernel.String(`"cancelled"`),
+	}); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if err := client.Invocations.DeleteBrowsers(ctx, res.ID); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+
+	inv, ok := srv.Invocation(res.ID)
+	if !ok || inv.Status != action.StatusFailed || inv.Output != `"cancelled"` {
+		t.Errorf("unexpected invocation: %+v", inv)
+	}
+	if srv.BrowsersDeleted(res.ID) != 1 {
+		t.Errorf("expected browsers to be deleted once")
+	}
+}
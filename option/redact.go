@@ -0,0 +1,60 @@
+package option
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+
+	"github.com/kernel/kernel-go-sdk/internal/redact"
+)
+
+// WithRedactedDebugLog is like [WithDebugLog], but replaces values marked with
+// kernel.MarkSensitive in the logged requests and responses.
+func WithRedactedDebugLog(logger *log.Logger) RequestOption {
+	return WithMiddleware(func(req *http.Request, nxt MiddlewareNext) (*http.Response, error) {
+		if logger == nil {
+			logger = log.Default()
+		}
+
+		if reqBytes, err := httputil.DumpRequest(req, true); err == nil {
+			logger.Printf("Request Content:\n%s\n", redact.Bytes(reqBytes))
+		}
+
+		resp, err := nxt(req)
+		if err != nil {
+			return resp, err
+		}
+
+		if respBytes, err := httputil.DumpResponse(resp, true); err == nil {
+			logger.Printf("Response Content:\n%s\n", redact.Bytes(respBytes))
+		}
+
+		return resp, err
+	})
+}
+
+// WithRedactedErrors replaces values marked with kernel.MarkSensitive in the
+// bodies of error responses, so they do not appear in the message or response
+// dump of the resulting kernel.Error. The request dump still holds what was
+// sent.
+func WithRedactedErrors() RequestOption {
+	return WithMiddleware(func(req *http.Request, nxt MiddlewareNext) (*http.Response, error) {
+		resp, err := nxt(req)
+		if err != nil || resp.StatusCode < 400 {
+			return resp, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return resp, err
+		}
+		body = redact.Bytes(body)
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		return resp, nil
+	})
+}
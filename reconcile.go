@@ -0,0 +1,462 @@
+package kernel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// ReconcileState declares the browser pools, profiles and proxies that should
+// exist, keyed by name. The Name field of each entry is set from its key, and
+// resources are matched to existing ones by name alone, so every key must be
+// non-empty. Profiles have no settings besides their name, so an existing
+// profile is never updated, only created or deleted.
+type ReconcileState struct {
+	BrowserPools map[string]BrowserPoolNewParams
+	Profiles     map[string]ProfileNewParams
+	Proxies      map[string]ProxyNewParams
+	// If true, named resources of a declared kind that are not declared are
+	// deleted. Unnamed resources are never deleted. A kind whose map is nil is
+	// left alone entirely.
+	Prune bool
+}
+
+// Kind of resource managed by [Client.Reconcile].
+type ReconcileKind string
+
+const (
+	ReconcileKindBrowserPool ReconcileKind = "browser_pool"
+	ReconcileKindProfile     ReconcileKind = "profile"
+	ReconcileKindProxy       ReconcileKind = "proxy"
+)
+
+// What [ReconcilePlan.Apply] does to a resource.
+type ReconcileAction string
+
+const (
+	ReconcileActionNoop   ReconcileAction = "no-op"
+	ReconcileActionCreate ReconcileAction = "create"
+	ReconcileActionUpdate ReconcileAction = "update"
+	// Proxies cannot be updated, so a changed proxy is deleted and created again,
+	// which gives it a new ID. [Client.Reconcile] refuses to replace a proxy
+	// that a browser pool keeps using.
+	ReconcileActionReplace ReconcileAction = "replace"
+	ReconcileActionDelete  ReconcileAction = "delete"
+)
+
+// ReconcileStep is the planned change to a single resource.
+type ReconcileStep struct {
+	Kind   ReconcileKind
+	Name   string
+	Action ReconcileAction
+	// ID of the existing resource, if any.
+	ID string
+	// Fields that differ, such as "size: 2 -> 5".
+	Changes []string
+	// Whether the pool's idle browsers are flushed after the update so they are
+	// rebuilt with the new configuration. Changes to the size and fill rate alone
+	// do not need a flush.
+	Flush bool
+
+	// The declared params, or the zero value of the kind's params for deletions.
+	params any
+	body   json.RawMessage
+}
+
+// ReconcilePlan is the set of changes that bring the account to a
+// [ReconcileState]. Steps are in the order Apply performs them.
+type ReconcilePlan struct {
+	Steps []ReconcileStep
+
+	client *Client
+	opts   []option.RequestOption
+}
+
+// HasChanges reports whether applying the plan would change anything.
+func (p *ReconcilePlan) HasChanges() bool {
+	for _, s := range p.Steps {
+		if s.Action != ReconcileActionNoop {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the plan one step per line, with the changed fields indented
+// below each step.
+func (p *ReconcilePlan) String() string {
+	var b strings.Builder
+	for _, s := range p.Steps {
+		fmt.Fprintf(&b, "%s %s %q", s.Action, s.Kind, s.Name)
+		if s.Flush {
+			b.WriteString(" (flush)")
+		}
+		b.WriteByte('\n')
+		for _, c := range s.Changes {
+			fmt.Fprintf(&b, "    %s\n", c)
+		}
+	}
+	return b.String()
+}
+
+// Reconcile compares state with the existing browser pools, profiles and
+// proxies and returns the plan that reconciles them. Nothing is changed until the
+// plan is applied. Only the fields set in state are compared, so fields left to
+// their server defaults, and fields the API does not return such as proxy
+// passwords, never cause an update.
+//
+// A proxy that would be replaced or deleted while a browser pool still refers
+// to its ID is an error, as the pool would be left with a proxy that no longer
+// exists. To change such a proxy, declare the new one under another name and
+// point the pools at it.
+func (r *Client) Reconcile(ctx context.Context, state ReconcileState, opts ...option.RequestOption) (res *ReconcilePlan, err error) {
+	for kind, names := range map[ReconcileKind][]string{
+		ReconcileKindProxy:       sortedKeys(state.Proxies),
+		ReconcileKindProfile:     sortedKeys(state.Profiles),
+		ReconcileKindBrowserPool: sortedKeys(state.BrowserPools),
+	} {
+		if len(names) > 0 && names[0] == "" {
+			return nil, fmt.Errorf("kernel: %s declared without a name", kind)
+		}
+	}
+	res = &ReconcilePlan{client: r, opts: opts}
+	var creates, updates, deletes []ReconcileStep
+	var pools *[]BrowserPool
+
+	if state.Proxies != nil {
+		existing, err := r.Proxies.List(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		actual := map[string]reconcileResource{}
+		for _, p := range *existing {
+			// Unnamed resources cannot be declared, so they are left alone.
+			if p.Name == "" {
+				continue
+			}
+			actual[p.Name] = reconcileResource{id: p.ID, raw: p.RawJSON()}
+		}
+		for _, name := range sortedKeys(state.Proxies) {
+			params := state.Proxies[name]
+			params.Name = String(name)
+			step, err := planReconcileStep(ReconcileKindProxy, name, params, actual)
+			if err != nil {
+				return nil, err
+			}
+			if step.Action == ReconcileActionUpdate {
+				step.Action = ReconcileActionReplace
+			}
+			updates = append(updates, step)
+		}
+		deletes = append(deletes, pruneReconcileSteps(ReconcileKindProxy, state.Prune, actual, state.Proxies)...)
+	}
+
+	if state.Profiles != nil {
+		existing, err := r.Profiles.List(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		actual := map[string]reconcileResource{}
+		for _, p := range *existing {
+			if p.Name == "" {
+				continue
+			}
+			actual[p.Name] = reconcileResource{id: p.ID}
+		}
+		for _, name := range sortedKeys(state.Profiles) {
+			params := state.Profiles[name]
+			params.Name = String(name)
+			step, err := planReconcileStep(ReconcileKindProfile, name, params, actual)
+			if err != nil {
+				return nil, err
+			}
+			updates = append(updates, step)
+		}
+		deletes = append(deletes, pruneReconcileSteps(ReconcileKindProfile, state.Prune, actual, state.Profiles)...)
+	}
+
+	if state.BrowserPools != nil {
+		existing, err := r.BrowserPools.List(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		pools = existing
+		actual := map[string]reconcileResource{}
+		for _, p := range *existing {
+			if p.Name == "" {
+				continue
+			}
+			actual[p.Name] = reconcileResource{id: p.ID, raw: p.BrowserPoolConfig.RawJSON()}
+		}
+		for _, name := range sortedKeys(state.BrowserPools) {
+			params := state.BrowserPools[name]
+			params.Name = String(name)
+			step, err := planReconcileStep(ReconcileKindBrowserPool, name, params, actual)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range step.Changes {
+				field, _, _ := strings.Cut(c, ":")
+				if field != "size" && field != "fill_rate_per_minute" {
+					step.Flush = step.Action == ReconcileActionUpdate
+				}
+			}
+			updates = append(updates, step)
+		}
+		deletes = append(deletes, pruneReconcileSteps(ReconcileKindBrowserPool, state.Prune, actual, state.BrowserPools)...)
+	}
+
+	if err := checkReconcileProxyUsers(ctx, r, updates, deletes, state.BrowserPools, pools, opts); err != nil {
+		return nil, err
+	}
+
+	// Proxies and profiles are created before the pools that may use them, and
+	// pools are deleted before them.
+	for _, s := range updates {
+		if s.Action == ReconcileActionCreate {
+			creates = append(creates, s)
+		}
+	}
+	res.Steps = append(res.Steps, creates...)
+	for _, s := range updates {
+		if s.Action != ReconcileActionCreate {
+			res.Steps = append(res.Steps, s)
+		}
+	}
+	sort.SliceStable(deletes, func(i, j int) bool {
+		return deletes[i].Kind == ReconcileKindBrowserPool && deletes[j].Kind != ReconcileKindBrowserPool
+	})
+	res.Steps = append(res.Steps, deletes...)
+	return res, nil
+}
+
+// checkReconcileProxyUsers returns an error if a proxy the plan replaces or
+// deletes is still used by a browser pool once the plan is applied. pools is
+// nil if the pools have not been listed yet.
+func checkReconcileProxyUsers(ctx context.Context, r *Client, updates, deletes []ReconcileStep, declared map[string]BrowserPoolNewParams, pools *[]BrowserPool, opts []option.RequestOption) error {
+	removed := map[string]ReconcileStep{}
+	deletedPools := map[string]bool{}
+	for _, s := range append(append([]ReconcileStep(nil), updates...), deletes...) {
+		switch {
+		case s.Kind == ReconcileKindProxy && (s.Action == ReconcileActionReplace || s.Action == ReconcileActionDelete):
+			removed[s.ID] = s
+		case s.Kind == ReconcileKindBrowserPool && s.Action == ReconcileActionDelete:
+			deletedPools[s.Name] = true
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	if pools == nil {
+		var err error
+		if pools, err = r.BrowserPools.List(ctx, opts...); err != nil {
+			return err
+		}
+	}
+
+	// The proxy each pool uses after the plan: the declared one if the pool is
+	// declared with a proxy, and its current one otherwise.
+	type proxyUse struct{ pool, proxyID string }
+	var uses []proxyUse
+	listed := map[string]bool{}
+	for _, p := range *pools {
+		listed[p.Name] = true
+		if p.Name != "" && deletedPools[p.Name] {
+			continue
+		}
+		use := proxyUse{pool: p.Name, proxyID: p.BrowserPoolConfig.ProxyID}
+		if p.Name == "" {
+			use.pool = p.ID
+		} else if params, ok := declared[p.Name]; ok && params.ProxyID.Valid() {
+			use.proxyID = params.ProxyID.Value
+		}
+		uses = append(uses, use)
+	}
+	for _, name := range sortedKeys(declared) {
+		if params := declared[name]; !listed[name] && params.ProxyID.Valid() {
+			uses = append(uses, proxyUse{pool: name, proxyID: params.ProxyID.Value})
+		}
+	}
+	for _, use := range uses {
+		if step, ok := removed[use.proxyID]; ok && use.proxyID != "" {
+			return fmt.Errorf("kernel: cannot %s proxy %q: browser pool %q uses it; declare the new proxy under another name and point the pool at it", step.Action, step.Name, use.pool)
+		}
+	}
+	return nil
+}
+
+// Apply performs the plan's steps in order and stops at the first failure.
+// Steps taken before the failure are not undone; reconciling again plans the
+// rest.
+func (p *ReconcilePlan) Apply(ctx context.Context) error {
+	r := p.client
+	for _, s := range p.Steps {
+		if err := p.apply(ctx, r, s); err != nil {
+			return fmt.Errorf("kernel: %s %s %q: %w", s.Action, s.Kind, s.Name, err)
+		}
+	}
+	return nil
+}
+
+func (p *ReconcilePlan) apply(ctx context.Context, r *Client, s ReconcileStep) (err error) {
+	opts := p.opts
+	switch body := s.params.(type) {
+	case ProxyNewParams:
+		if s.Action == ReconcileActionReplace || s.Action == ReconcileActionDelete {
+			err = r.Proxies.Delete(ctx, s.ID, opts...)
+		}
+		if err == nil && (s.Action == ReconcileActionReplace || s.Action == ReconcileActionCreate) {
+			_, err = r.Proxies.New(ctx, body, opts...)
+		}
+	case ProfileNewParams:
+		switch s.Action {
+		case ReconcileActionCreate:
+			_, err = r.Profiles.New(ctx, body, opts...)
+		case ReconcileActionDelete:
+			err = r.Profiles.Delete(ctx, s.ID, opts...)
+		}
+	case BrowserPoolNewParams:
+		switch s.Action {
+		case ReconcileActionCreate:
+			_, err = r.BrowserPools.New(ctx, body, opts...)
+		case ReconcileActionUpdate:
+			// The update takes the same fields as the creation.
+			var update BrowserPoolUpdateParams
+			if err := json.Unmarshal(s.body, &update); err != nil {
+				return err
+			}
+			if _, err = r.BrowserPools.Update(ctx, s.ID, update, opts...); err == nil && s.Flush {
+				err = r.BrowserPools.Flush(ctx, s.ID, opts...)
+			}
+		case ReconcileActionDelete:
+			err = r.BrowserPools.Delete(ctx, s.ID, BrowserPoolDeleteParams{}, opts...)
+		}
+	}
+	return err
+}
+
+type reconcileResource struct {
+	id  string
+	raw string
+}
+
+func planReconcileStep(kind ReconcileKind, name string, params any, actual map[string]reconcileResource) (ReconcileStep, error) {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return ReconcileStep{}, fmt.Errorf("kernel: %s %q: %w", kind, name, err)
+	}
+	step := ReconcileStep{Kind: kind, Name: name, params: params, body: body}
+	existing, ok := actual[name]
+	if !ok {
+		step.Action = ReconcileActionCreate
+		return step, nil
+	}
+	step.ID = existing.id
+	step.Action = ReconcileActionNoop
+	if existing.raw == "" {
+		return step, nil
+	}
+	var want, have map[string]any
+	if err := decodeJSONNumbers(body, &want); err != nil {
+		return ReconcileStep{}, err
+	}
+	if err := decodeJSONNumbers([]byte(existing.raw), &have); err != nil {
+		return ReconcileStep{}, fmt.Errorf("kernel: decoding %s %q: %w", kind, name, err)
+	}
+	delete(want, "name")
+	step.Changes = diffReconcileFields("", want, have)
+	if len(step.Changes) > 0 {
+		step.Action = ReconcileActionUpdate
+	}
+	return step, nil
+}
+
+func pruneReconcileSteps[T any](kind ReconcileKind, prune bool, actual map[string]reconcileResource, declared map[string]T) []ReconcileStep {
+	if !prune {
+		return nil
+	}
+	var zero T
+	var steps []ReconcileStep
+	for name, res := range actual {
+		if _, ok := declared[name]; !ok && name != "" {
+			steps = append(steps, ReconcileStep{Kind: kind, Name: name, ID: res.id, Action: ReconcileActionDelete, params: zero})
+		}
+	}
+	sort.Slice(steps, func(i, j int) bool { return steps[i].Name < steps[j].Name })
+	return steps
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func decodeJSONNumbers(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// diffReconcileFields lists the fields of want that differ in have. Objects are
+// compared field by field, so fields only present in have are ignored, and so
+// are fields of want that have does not report at all.
+func diffReconcileFields(prefix string, want, have map[string]any) []string {
+	var changes []string
+	for _, k := range sortedKeys(want) {
+		path := prefix + k
+		w, h := want[k], have[k]
+		if _, reported := have[k]; !reported && prefix != "" {
+			continue
+		}
+		wm, wok := w.(map[string]any)
+		hm, hok := h.(map[string]any)
+		if wok && hok {
+			changes = append(changes, diffReconcileFields(path+".", wm, hm)...)
+			continue
+		}
+		if !reconcileValueMatches(w, h) {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", path, compactJSON(h), compactJSON(w)))
+		}
+	}
+	return changes
+}
+
+func reconcileValueMatches(want, have any) bool {
+	wm, wok := want.(map[string]any)
+	hm, hok := have.(map[string]any)
+	if wok && hok {
+		return len(diffReconcileFields(".", wm, hm)) == 0
+	}
+	ws, wok := want.([]any)
+	hs, hok := have.([]any)
+	if wok && hok {
+		if len(ws) != len(hs) {
+			return false
+		}
+		for i := range ws {
+			if !reconcileValueMatches(ws[i], hs[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(want, have)
+}
+
+func compactJSON(v any) string {
+	if v == nil {
+		return "unset"
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
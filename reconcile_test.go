@@ -0,0 +1,187 @@
+package kernel_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// newReconcileServer serves existing proxies, profiles and browser pools, plus
+// extraPools, and records the changes made to them.
+func newReconcileServer(t *testing.T, extraPools ...string) (*[]string, kernel.Client) {
+	var mu sync.Mutex
+	var calls []string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/proxies":
+			fmt.Fprint(w, `[{"id":"proxy_eu","name":"eu","type":"datacenter","config":{"country":"DE"}},{"id":"proxy_old","name":"old","type":"datacenter","config":{"country":"US"}}]`)
+			return
+		case r.Method == http.MethodGet && r.URL.Path == "/profiles":
+			fmt.Fprint(w, `[{"id":"prof_shop","name":"shop","created_at":"2025-01-01T00:00:00Z"},{"id":"prof_anon","created_at":"2025-01-01T00:00:00Z"}]`)
+			return
+		case r.Method == http.MethodGet && r.URL.Path == "/browser_pools":
+			fmt.Fprintf(w, `[
+				{"id":"pool_a","name":"scrapers","acquired_count":0,"available_count":2,"created_at":"2025-01-01T00:00:00Z","browser_pool_config":{"name":"scrapers","size":2,"headless":true,"stealth":false,"timeout_seconds":600,"fill_rate_per_minute":10}},
+				{"id":"pool_b","name":"checkout","acquired_count":0,"available_count":1,"created_at":"2025-01-01T00:00:00Z","browser_pool_config":{"name":"checkout","size":1,"headless":false,"stealth":false,"timeout_seconds":600}},
+				{"id":"pool_c","name":"legacy","acquired_count":0,"available_count":1,"created_at":"2025-01-01T00:00:00Z","browser_pool_config":{"name":"legacy","size":1}}%s
+			]`, strings.Join(append([]string{""}, extraPools...), ","))
+			return
+		}
+		mu.Lock()
+		if len(body) > 0 {
+			b, _ := json.Marshal(body)
+			calls = append(calls, r.Method+" "+r.URL.Path+" "+string(b))
+		} else {
+			calls = append(calls, r.Method+" "+r.URL.Path)
+		}
+		mu.Unlock()
+		switch r.Method {
+		case http.MethodPost:
+			if strings.HasSuffix(r.URL.Path, "/flush") {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			fmt.Fprint(w, `{"id":"new","name":"new"}`)
+		case http.MethodPatch:
+			fmt.Fprint(w, `{"id":"pool","browser_pool_config":{"size":1}}`)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}), option.WithMaxRetries(0))
+	return &calls, client
+}
+
+func reconcileTestState(prune bool) kernel.ReconcileState {
+	return kernel.ReconcileState{
+		Proxies: map[string]kernel.ProxyNewParams{
+			"eu": {
+				Type: kernel.ProxyNewParamsTypeDatacenter,
+				Config: kernel.ProxyNewParamsConfigUnion{
+					OfProxyNewsConfigDatacenterProxyConfig: &kernel.ProxyNewParamsConfigDatacenterProxyConfig{Country: kernel.String("FR")},
+				},
+			},
+		},
+		Profiles: map[string]kernel.ProfileNewParams{
+			"shop":  {},
+			"login": {},
+		},
+		BrowserPools: map[string]kernel.BrowserPoolNewParams{
+			"scrapers": {Size: 5, Headless: kernel.Bool(true)},
+			"checkout": {Size: 1, Stealth: kernel.Bool(true)},
+		},
+		Prune: prune,
+	}
+}
+
+func TestReconcilePlan(t *testing.T) {
+	calls, client := newReconcileServer(t)
+	plan, err := client.Reconcile(context.Background(), reconcileTestState(true))
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	want := `create profile "login"
+replace proxy "eu"
+    config.country: "DE" -> "FR"
+no-op profile "shop"
+update browser_pool "checkout" (flush)
+    stealth: false -> true
+update browser_pool "scrapers"
+    size: 2 -> 5
+delete browser_pool "legacy"
+delete proxy "old"
+`
+	if got := plan.String(); got != want {
+		t.Errorf("unexpected plan:\n%s\nwant:\n%s", got, want)
+	}
+	if !plan.HasChanges() {
+		t.Error("expected the plan to have changes")
+	}
+	if len(*calls) != 0 {
+		t.Errorf("expected planning to change nothing, got %v", *calls)
+	}
+}
+
+func TestReconcileApply(t *testing.T) {
+	calls, client := newReconcileServer(t)
+	plan, err := client.Reconcile(context.Background(), reconcileTestState(true))
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if err := plan.Apply(context.Background()); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	want := []string{
+		`POST /profiles {"name":"login"}`,
+		`DELETE /proxies/proxy_eu`,
+		`POST /proxies {"config":{"country":"FR"},"name":"eu","type":"datacenter"}`,
+		`PATCH /browser_pools/pool_b {"name":"checkout","size":1,"stealth":true}`,
+		`POST /browser_pools/pool_b/flush`,
+		`PATCH /browser_pools/pool_a {"headless":true,"name":"scrapers","size":5}`,
+		`DELETE /browser_pools/pool_c`,
+		`DELETE /proxies/proxy_old`,
+	}
+	if strings.Join(*calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected calls:\n%s\nwant:\n%s", strings.Join(*calls, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReconcileWithoutPrune(t *testing.T) {
+	_, client := newReconcileServer(t)
+	state := reconcileTestState(false)
+	state.Proxies = nil
+	plan, err := client.Reconcile(context.Background(), state)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	for _, s := range plan.Steps {
+		if s.Action == kernel.ReconcileActionDelete || s.Kind == kernel.ReconcileKindProxy {
+			t.Errorf("unexpected step %s %s %q", s.Action, s.Kind, s.Name)
+		}
+	}
+}
+
+func TestReconcileProxyInUse(t *testing.T) {
+	_, client := newReconcileServer(t, `{"id":"pool_d","name":"geo","acquired_count":0,"available_count":1,"created_at":"2025-01-01T00:00:00Z","browser_pool_config":{"name":"geo","size":1,"proxy_id":"proxy_eu"}}`)
+	_, err := client.Reconcile(context.Background(), reconcileTestState(false))
+	if err == nil || !strings.Contains(err.Error(), `cannot replace proxy "eu": browser pool "geo" uses it`) {
+		t.Errorf("expected replacing a proxy in use to be refused, got %v", err)
+	}
+
+	// Pruning deletes the pool before the proxy is replaced.
+	plan, err := client.Reconcile(context.Background(), reconcileTestState(true))
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if !strings.Contains(plan.String(), `delete browser_pool "geo"`) {
+		t.Errorf("expected the pool to be deleted, got:\n%s", plan)
+	}
+
+	// A pool moved to another proxy no longer uses the replaced one.
+	state := reconcileTestState(false)
+	state.BrowserPools["geo"] = kernel.BrowserPoolNewParams{Size: 1, ProxyID: kernel.String("proxy_other")}
+	if _, err := client.Reconcile(context.Background(), state); err != nil {
+		t.Errorf("err should be nil: %s", err.Error())
+	}
+}
+
+func TestReconcileUnnamed(t *testing.T) {
+	calls, client := newReconcileServer(t)
+	state := reconcileTestState(true)
+	state.Profiles[""] = kernel.ProfileNewParams{}
+	if _, err := client.Reconcile(context.Background(), state); err == nil || err.Error() != "kernel: profile declared without a name" {
+		t.Errorf("expected an unnamed profile to be rejected, got %v", err)
+	}
+	if len(*calls) != 0 {
+		t.Errorf("expected nothing to change, got %v", *calls)
+	}
+}
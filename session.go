@@ -0,0 +1,86 @@
+package kernel
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// NewSession creates a browser session and returns it as a [Session].
+func (r *BrowserService) NewSession(ctx context.Context, body BrowserNewParams, opts ...option.RequestOption) (res *Session, err error) {
+	browser, err := r.New(ctx, body, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newSession(r, *browser), nil
+}
+
+// GetSession looks up an existing browser session and returns it as a
+// [Session].
+func (r *BrowserService) GetSession(ctx context.Context, id string, opts ...option.RequestOption) (res *Session, err error) {
+	browser, err := r.Get(ctx, id, BrowserGetParams{}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newSession(r, BrowserNewResponse(*browser)), nil
+}
+
+// AcquireSession acquires a browser from the pool and returns it as a [Session].
+// Unlike [BrowserPoolService.Acquire], it keeps polling when a poll times out
+// without a browser, waiting with exponential backoff between polls, until one
+// is acquired or ctx is done. Return the browser to the pool with
+// [BrowserPoolService.Release].
+func (r *BrowserPoolService) AcquireSession(ctx context.Context, idOrName string, body BrowserPoolAcquireParams, opts ...option.RequestOption) (res *Session, err error) {
+	return r.acquireWithBackoff(ctx, idOrName, body.AcquireTimeoutSeconds, time.Second, opts)
+}
+
+// Refresh reloads the session's metadata.
+func (s *Session) Refresh(ctx context.Context, opts ...option.RequestOption) error {
+	browser, err := s.browsers.Get(ctx, s.ID(), BrowserGetParams{}, opts...)
+	if err != nil {
+		return err
+	}
+	s.browserMu.Lock()
+	s.browser = BrowserNewResponse(*browser)
+	s.browserMu.Unlock()
+	return nil
+}
+
+// Delete deletes the browser session and stops its keepalive.
+func (s *Session) Delete(ctx context.Context, opts ...option.RequestOption) error {
+	s.keepaliveDone()
+	return s.browsers.DeleteByID(ctx, s.ID(), opts...)
+}
+
+// Screenshot captures the whole screen as a PNG image.
+func (s *Session) Screenshot(ctx context.Context, opts ...option.RequestOption) ([]byte, error) {
+	res, err := s.Computer.CaptureScreenshot(ctx, BrowserComputerCaptureScreenshotParams{}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return io.ReadAll(res.Body)
+}
+
+// Exec runs a command in the browser's environment and waits for it to finish.
+func (s *Session) Exec(ctx context.Context, command string, args []string, opts ...option.RequestOption) (*BrowserProcessExecResponse, error) {
+	return s.Process.Exec(ctx, BrowserProcessExecParams{Command: command, Args: args}, opts...)
+}
+
+// ReadFile returns the contents of the file at the absolute path name.
+func (s *Session) ReadFile(ctx context.Context, name string, opts ...option.RequestOption) ([]byte, error) {
+	res, err := s.Fs.ReadFile(ctx, BrowserFReadFileParams{Path: name}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return io.ReadAll(res.Body)
+}
+
+// WriteFile creates or replaces the file at the absolute path name.
+func (s *Session) WriteFile(ctx context.Context, name string, data []byte, opts ...option.RequestOption) error {
+	return s.Fs.WriteFile(ctx, bytes.NewReader(data), BrowserFWriteFileParams{Path: name}, opts...)
+}
@@ -0,0 +1,87 @@
+package kernel_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk"
+)
+
+const sessionJSON = `{"session_id":"sess_1","cdp_ws_url":"wss://cdp","browser_live_view_url":"https://live","created_at":"2025-01-01T00:00:00Z","headless":false,"stealth":false,"timeout_seconds":60,"viewport":{"width":1920,"height":1080}}`
+
+func TestSession(t *testing.T) {
+	var written string
+	polls := 0
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/browsers":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, sessionJSON)
+		case r.Method == http.MethodPost && r.URL.Path == "/browser_pools/pool/acquire":
+			polls++
+			if polls < 3 {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, sessionJSON)
+		case r.Method == http.MethodPost && r.URL.Path == "/browsers/sess_1/computer/screenshot":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG"))
+		case r.Method == http.MethodPost && r.URL.Path == "/browsers/sess_1/process/exec":
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["command"] != "ls" || fmt.Sprint(body["args"]) != "[-la]" {
+				t.Errorf("unexpected exec body: %v", body)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"exit_code":0,"stdout_b64":"b2s="}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/browsers/sess_1/fs/read_file":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			fmt.Fprint(w, "contents of "+r.URL.Query().Get("path"))
+		case r.Method == http.MethodPut && r.URL.Path == "/browsers/sess_1/fs/write_file":
+			b, _ := io.ReadAll(r.Body)
+			written = r.URL.Query().Get("path") + "=" + string(b)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	ctx := context.Background()
+
+	s, err := client.Browsers.NewSession(ctx, kernel.BrowserNewParams{})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if b := s.Browser(); s.ID() != "sess_1" || b.CdpWsURL != "wss://cdp" || b.BrowserLiveViewURL != "https://live" || b.Viewport.Width != 1920 {
+		t.Errorf("unexpected session metadata: %+v", b)
+	}
+
+	png, err := s.Screenshot(ctx)
+	if err != nil || string(png) != "\x89PNG" {
+		t.Errorf("Screenshot() = %q, %v", png, err)
+	}
+	res, err := s.Exec(ctx, "ls", []string{"-la"})
+	if err != nil || res.StdoutB64 != "b2s=" {
+		t.Errorf("Exec() = %+v, %v", res, err)
+	}
+	data, err := s.ReadFile(ctx, "/tmp/a.txt")
+	if err != nil || string(data) != "contents of /tmp/a.txt" {
+		t.Errorf("ReadFile() = %q, %v", data, err)
+	}
+	if err := s.WriteFile(ctx, "/tmp/b.txt", []byte("hello")); err != nil || written != "/tmp/b.txt=hello" {
+		t.Errorf("WriteFile() wrote %q, %v", written, err)
+	}
+
+	pooled, err := client.BrowserPools.AcquireSession(ctx, "pool", kernel.BrowserPoolAcquireParams{})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if pooled.ID() != "sess_1" || polls != 3 {
+		t.Errorf("expected polling to continue until a browser is acquired, got %d polls", polls)
+	}
+}
@@ -0,0 +1,336 @@
+package kernel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// SessionFS is the filesystem of a browser session as an [fs.FS]. It also
+// implements [fs.ReadDirFS], [fs.ReadFileFS] and [fs.StatFS], so it works with
+// [fs.WalkDir], [fs.Glob], [html/template.ParseFS] and [net/http.FS].
+//
+// Names are slash-separated paths relative to the root of the browser's
+// filesystem, as [fs.ValidPath] requires: "etc/hosts" is "/etc/hosts", and "."
+// is the root. Files the API reports missing return errors matching
+// [fs.ErrNotExist].
+type SessionFS struct {
+	fs   BrowserSessionFs
+	ctx  context.Context
+	opts []option.RequestOption
+}
+
+// FS returns the session's filesystem. Its requests use
+// [context.Background]; use [SessionFS.WithContext] to bound them.
+func (s *Session) FS(opts ...option.RequestOption) *SessionFS {
+	return &SessionFS{fs: s.Fs, ctx: context.Background(), opts: opts}
+}
+
+// WithContext returns a copy of the filesystem whose requests use ctx.
+func (f *SessionFS) WithContext(ctx context.Context) *SessionFS {
+	c := *f
+	c.ctx = ctx
+	return &c
+}
+
+// Open opens the named file or directory. The contents of a file are
+// downloaded in full on the first Read, Seek or ReadAt.
+func (f *SessionFS) Open(name string) (fs.File, error) {
+	info, err := f.Stat(name)
+	if err != nil {
+		return nil, replacePathErrorOp(err, "open")
+	}
+	return &sessionFile{fsys: f, name: name, info: info}, nil
+}
+
+// Stat returns a [fs.FileInfo] describing the named file. Its Sys method
+// returns the [*BrowserFFileInfoResponse].
+func (f *SessionFS) Stat(name string) (fs.FileInfo, error) {
+	p, err := sessionFSPath("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	res, err := f.fs.FileInfo(f.ctx, BrowserFFileInfoParams{Path: p}, f.opts...)
+	if err != nil {
+		return nil, sessionFSError("stat", name, err)
+	}
+	return newSessionFileInfo(res.Name, res.SizeBytes, res.Mode, res.ModTime, res.IsDir, res), nil
+}
+
+// ReadDir reads the named directory and returns its entries sorted by name.
+// The Sys method of their Info returns the [BrowserFListFilesResponse].
+func (f *SessionFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := sessionFSPath("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	res, err := f.fs.ListFiles(f.ctx, BrowserFListFilesParams{Path: p}, f.opts...)
+	if err != nil {
+		return nil, sessionFSError("readdir", name, err)
+	}
+	entries := make([]fs.DirEntry, 0, len(*res))
+	for _, e := range *res {
+		entries = append(entries, fs.FileInfoToDirEntry(newSessionFileInfo(e.Name, e.SizeBytes, e.Mode, e.ModTime, e.IsDir, e)))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// ReadFile returns the contents of the named file.
+func (f *SessionFS) ReadFile(name string) ([]byte, error) {
+	p, err := sessionFSPath("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	res, err := f.fs.ReadFile(f.ctx, BrowserFReadFileParams{Path: p}, f.opts...)
+	if err != nil {
+		return nil, sessionFSError("readfile", name, err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, sessionFSError("readfile", name, err)
+	}
+	return data, nil
+}
+
+// sessionFSPath returns the absolute path of an fs.FS name.
+func sessionFSPath(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join("/", name), nil
+}
+
+// sessionFSError maps API errors to the fs package's errors.
+func sessionFSError(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: fsAPIError(err)}
+}
+
+func fsAPIError(err error) error {
+	var apierr *Error
+	if errors.As(err, &apierr) {
+		switch apierr.StatusCode {
+		case http.StatusNotFound:
+			return fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+		case http.StatusForbidden:
+			return fmt.Errorf("%w: %w", fs.ErrPermission, err)
+		case http.StatusConflict:
+			return fmt.Errorf("%w: %w", fs.ErrExist, err)
+		case http.StatusBadRequest:
+			return fmt.Errorf("%w: %w", fs.ErrInvalid, err)
+		}
+	}
+	return err
+}
+
+func replacePathErrorOp(err error, op string) error {
+	var perr *fs.PathError
+	if errors.As(err, &perr) {
+		return &fs.PathError{Op: op, Path: perr.Path, Err: perr.Err}
+	}
+	return err
+}
+
+// ParseFileMode parses a file mode as formatted by [fs.FileMode.String], such
+// as "drwxr-xr-x" or "Lrwxrwxrwx", or by ls, such as "lrwxrwxrwx" or
+// "-rwsr-xr-x".
+func ParseFileMode(s string) (fs.FileMode, error) {
+	if len(s) < 9 {
+		return 0, fmt.Errorf("kernel: invalid file mode %q", s)
+	}
+	prefix, perm := s[:len(s)-9], s[len(s)-9:]
+	var mode fs.FileMode
+	for i := 0; i < len(perm); i++ {
+		c := perm[i]
+		bit := fs.FileMode(1) << (8 - i)
+		switch {
+		case c == '-':
+		case c == "rwxrwxrwx"[i]:
+			mode |= bit
+		// ls shows the setuid, setgid and sticky bits in place of the execute
+		// bits, in lower case if the execute bit is set too.
+		case i == 2 && (c == 's' || c == 'S'):
+			mode |= fs.ModeSetuid
+		case i == 5 && (c == 's' || c == 'S'):
+			mode |= fs.ModeSetgid
+		case i == 8 && (c == 't' || c == 'T'):
+			mode |= fs.ModeSticky
+		default:
+			return 0, fmt.Errorf("kernel: invalid file mode %q", s)
+		}
+		if c == 's' || c == 't' {
+			mode |= bit
+		}
+	}
+
+	if len(prefix) == 1 {
+		// A single type character, which ls and fs.FileMode.String mostly agree
+		// on, except that ls uses "l" for symbolic links.
+		switch prefix[0] {
+		case '-':
+		case 'd':
+			mode |= fs.ModeDir
+		case 'l', 'L':
+			mode |= fs.ModeSymlink
+		case 'c':
+			mode |= fs.ModeDevice | fs.ModeCharDevice
+		case 'b', 'D':
+			mode |= fs.ModeDevice
+		case 'p':
+			mode |= fs.ModeNamedPipe
+		case 's', 'S':
+			mode |= fs.ModeSocket
+		case 'u':
+			mode |= fs.ModeSetuid
+		case 'g':
+			mode |= fs.ModeSetgid
+		case 't':
+			mode |= fs.ModeSticky
+		case 'a':
+			mode |= fs.ModeAppend
+		case 'T':
+			mode |= fs.ModeTemporary
+		case '?':
+			mode |= fs.ModeIrregular
+		default:
+			return 0, fmt.Errorf("kernel: invalid file mode %q", s)
+		}
+		return mode, nil
+	}
+	// Several characters can only be fs.FileMode.String's.
+	const letters = "dalTLDpSugct?"
+	for _, c := range prefix {
+		i := strings.IndexRune(letters, c)
+		if i < 0 {
+			return 0, fmt.Errorf("kernel: invalid file mode %q", s)
+		}
+		mode |= fs.FileMode(1) << (31 - i)
+	}
+	return mode, nil
+}
+
+type sessionFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	sys     any
+}
+
+func newSessionFileInfo(name string, size int64, mode string, modTime time.Time, isDir bool, sys any) *sessionFileInfo {
+	// An unparsable mode still leaves the type known from isDir.
+	m, _ := ParseFileMode(mode)
+	if isDir {
+		m |= fs.ModeDir
+	}
+	return &sessionFileInfo{name: name, size: size, mode: m, modTime: modTime, sys: sys}
+}
+
+func (i *sessionFileInfo) Name() string       { return i.name }
+func (i *sessionFileInfo) Size() int64        { return i.size }
+func (i *sessionFileInfo) Mode() fs.FileMode  { return i.mode }
+func (i *sessionFileInfo) ModTime() time.Time { return i.modTime }
+func (i *sessionFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *sessionFileInfo) Sys() any           { return i.sys }
+
+// sessionFile is a file or directory opened by SessionFS.Open. It implements
+// fs.ReadDirFile, io.Seeker and io.ReaderAt.
+type sessionFile struct {
+	fsys *SessionFS
+	name string
+	info fs.FileInfo
+
+	data    *bytes.Reader
+	entries []fs.DirEntry
+	listed  bool
+	closed  bool
+}
+
+func (f *sessionFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// load downloads the file's contents.
+func (f *sessionFile) load(op string) error {
+	if f.closed {
+		return &fs.PathError{Op: op, Path: f.name, Err: fs.ErrClosed}
+	}
+	if f.info.IsDir() {
+		return &fs.PathError{Op: op, Path: f.name, Err: errors.New("is a directory")}
+	}
+	if f.data == nil {
+		data, err := f.fsys.ReadFile(f.name)
+		if err != nil {
+			return replacePathErrorOp(err, op)
+		}
+		f.data = bytes.NewReader(data)
+	}
+	return nil
+}
+
+func (f *sessionFile) Read(p []byte) (int, error) {
+	if err := f.load("read"); err != nil {
+		return 0, err
+	}
+	return f.data.Read(p)
+}
+
+func (f *sessionFile) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.load("read"); err != nil {
+		return 0, err
+	}
+	return f.data.ReadAt(p, off)
+}
+
+func (f *sessionFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.load("seek"); err != nil {
+		return 0, err
+	}
+	return f.data.Seek(offset, whence)
+}
+
+func (f *sessionFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if f.closed {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: fs.ErrClosed}
+	}
+	if !f.info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errors.New("not a directory")}
+	}
+	if !f.listed {
+		entries, err := f.fsys.ReadDir(f.name)
+		if err != nil {
+			return nil, err
+		}
+		f.entries, f.listed = entries, true
+	}
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
+
+func (f *sessionFile) Close() error {
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	f.data = nil
+	return nil
+}
@@ -0,0 +1,259 @@
+package kernel_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// fsServer is a browser filesystem holding files, keyed by absolute path.
+// Directories are implied by the paths or created explicitly.
+type fsServer struct {
+	mu    sync.Mutex
+	files map[string]string
+	dirs  map[string]bool
+	// Octal modes set by writes, mkdirs and chmods.
+	modes map[string]string
+}
+
+// newFSServer serves files from a browser session's filesystem.
+func newFSServer(t *testing.T, files map[string]string) *kernel.Session {
+	_, session := startFSServer(t, files)
+	return session
+}
+
+func startFSServer(t *testing.T, files map[string]string) (*fsServer, *kernel.Session) {
+	s := &fsServer{files: files, dirs: map[string]bool{"/": true}, modes: map[string]string{}}
+	for p := range files {
+		for d := path.Dir(p); d != "/"; d = path.Dir(d) {
+			s.dirs[d] = true
+		}
+	}
+	client := newTestClient(t, s, option.WithMaxRetries(0))
+	session, err := client.Browsers.GetSession(context.Background(), "sess_1")
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	return s, session
+}
+
+func (s *fsServer) info(p string) string {
+	mode := "-rw-r--r--"
+	if s.dirs[p] {
+		mode = "drwxr-xr-x"
+	}
+	if m, err := strconv.ParseUint(s.modes[p], 8, 32); err == nil && m <= 0o777 {
+		mode = fs.FileMode(m).String()
+		if s.dirs[p] {
+			mode = "d" + mode[1:]
+		}
+	}
+	return fmt.Sprintf(`{"name":%q,"path":%q,"is_dir":%t,"mode":%q,"size_bytes":%d,"mod_time":"2025-01-01T00:00:00Z"}`, path.Base(p), p, s.dirs[p], mode, len(s.files[p]))
+}
+
+func (s *fsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var body struct {
+		Path     string `json:"path"`
+		Mode     string `json:"mode"`
+		SrcPath  string `json:"src_path"`
+		DestPath string `json:"dest_path"`
+	}
+	p := r.URL.Query().Get("path")
+	if r.Method == http.MethodPut && r.URL.Path != "/browsers/sess_1/fs/write_file" {
+		json.NewDecoder(r.Body).Decode(&body)
+		p = body.Path
+		if r.URL.Path == "/browsers/sess_1/fs/move" {
+			p = body.SrcPath
+		}
+	}
+	_, isFile := s.files[p]
+	exists := isFile || s.dirs[p]
+	fail := func(status int, code string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"code":%q,"message":%q}`, code, p)
+	}
+	switch r.URL.Path {
+	case "/browsers/sess_1":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"session_id":"sess_1","cdp_ws_url":"wss://cdp","created_at":"2025-01-01T00:00:00Z","headless":true,"stealth":false,"timeout_seconds":60}`)
+	case "/browsers/sess_1/fs/write_file", "/browsers/sess_1/fs/create_directory":
+		switch {
+		case !s.dirs[path.Dir(p)]:
+			fail(http.StatusNotFound, "not_found")
+		case r.URL.Path == "/browsers/sess_1/fs/create_directory" && exists:
+			fail(http.StatusConflict, "conflict")
+		case s.dirs[p]:
+			fail(http.StatusBadRequest, "bad_request")
+		case r.URL.Path == "/browsers/sess_1/fs/create_directory":
+			s.dirs[p] = true
+			s.modes[p] = body.Mode
+			w.WriteHeader(http.StatusOK)
+		default:
+			data, _ := io.ReadAll(r.Body)
+			s.files[p] = string(data)
+			s.modes[p] = r.URL.Query().Get("mode")
+			w.WriteHeader(http.StatusOK)
+		}
+	case "/browsers/sess_1/fs/file_info", "/browsers/sess_1/fs/list_files", "/browsers/sess_1/fs/read_file",
+		"/browsers/sess_1/fs/delete_file", "/browsers/sess_1/fs/delete_directory", "/browsers/sess_1/fs/move",
+		"/browsers/sess_1/fs/set_file_permissions":
+		if !exists {
+			fail(http.StatusNotFound, "not_found")
+			return
+		}
+		s.serveExisting(w, r, p, body.Mode, body.DestPath)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveExisting handles the requests on an existing path p.
+func (s *fsServer) serveExisting(w http.ResponseWriter, r *http.Request, p, mode, dest string) {
+	switch path.Base(r.URL.Path) {
+	case "file_info":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, s.info(p))
+	case "list_files":
+		var entries []string
+		for q := range s.files {
+			if path.Dir(q) == p {
+				entries = append(entries, s.info(q))
+			}
+		}
+		for q := range s.dirs {
+			if q != "/" && path.Dir(q) == p {
+				entries = append(entries, s.info(q))
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "["+strings.Join(entries, ",")+"]")
+	case "read_file":
+		w.Header().Set("Content-Type", "application/octet-stream")
+		fmt.Fprint(w, s.files[p])
+	case "delete_file":
+		delete(s.files, p)
+	case "delete_directory":
+		// The API removes directories recursively.
+		for q := range s.files {
+			if strings.HasPrefix(q, p+"/") {
+				delete(s.files, q)
+			}
+		}
+		for q := range s.dirs {
+			if q == p || strings.HasPrefix(q, p+"/") {
+				delete(s.dirs, q)
+			}
+		}
+	case "move":
+		for q, data := range s.files {
+			if q == p || strings.HasPrefix(q, p+"/") {
+				delete(s.files, q)
+				s.files[dest+strings.TrimPrefix(q, p)] = data
+			}
+		}
+		for q := range s.dirs {
+			if q == p || strings.HasPrefix(q, p+"/") {
+				delete(s.dirs, q)
+				s.dirs[dest+strings.TrimPrefix(q, p)] = true
+			}
+		}
+	case "set_file_permissions":
+		s.modes[p] = mode
+	}
+}
+
+func TestSessionFS(t *testing.T) {
+	session := newFSServer(t, map[string]string{
+		"/tmp/downloads/report.csv": "a,b\n1,2\n",
+		"/tmp/downloads/page.html":  "<h1>{{.}}</h1>",
+		"/etc/hostname":             "browser\n",
+	})
+	fsys := session.FS()
+	if err := fstest.TestFS(fsys, "tmp/downloads/report.csv", "tmp/downloads/page.html", "etc/hostname"); err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := fs.Glob(fsys, "tmp/downloads/*.csv")
+	if err != nil || fmt.Sprint(matches) != "[tmp/downloads/report.csv]" {
+		t.Errorf("unexpected matches %v, %v", matches, err)
+	}
+
+	info, err := fs.Stat(fsys, "tmp/downloads")
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if !info.IsDir() || info.Mode().String() != "drwxr-xr-x" {
+		t.Errorf("unexpected mode %s", info.Mode())
+	}
+	if _, ok := info.Sys().(*kernel.BrowserFFileInfoResponse); !ok {
+		t.Errorf("expected the API response from Sys, got %T", info.Sys())
+	}
+
+	if _, err := fsys.ReadFile("tmp/missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a missing file not to exist, got %v", err)
+	}
+	if _, err := fsys.Open("/etc/hostname"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("expected an absolute name to be invalid, got %v", err)
+	}
+}
+
+func TestSessionFSFileServer(t *testing.T) {
+	session := newFSServer(t, map[string]string{"/srv/index.txt": "hello"})
+	srv := httptest.NewServer(http.FileServer(http.FS(session.FS())))
+	defer srv.Close()
+	res, err := http.Get(srv.URL + "/srv/index.txt")
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Errorf("unexpected response %d %q", res.StatusCode, body)
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	for _, mode := range []fs.FileMode{
+		0644,
+		fs.ModeDir | 0755,
+		fs.ModeSymlink | 0777,
+		fs.ModeDevice | fs.ModeCharDevice | 0620,
+		fs.ModeSetuid | fs.ModeSetgid | 0755,
+		fs.ModeNamedPipe | 0600,
+	} {
+		got, err := kernel.ParseFileMode(mode.String())
+		if err != nil || got != mode {
+			t.Errorf("ParseFileMode(%q) = %v, %v", mode.String(), got, err)
+		}
+	}
+	for s, want := range map[string]fs.FileMode{
+		"lrwxrwxrwx": fs.ModeSymlink | 0777,
+		"-rwsr-xr-x": fs.ModeSetuid | 0755,
+		"drwxrwxrwt": fs.ModeDir | fs.ModeSticky | 0777,
+		"-rw-r-Sr--": fs.ModeSetgid | 0644,
+	} {
+		if got, err := kernel.ParseFileMode(s); err != nil || got != want {
+			t.Errorf("ParseFileMode(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if _, err := kernel.ParseFileMode("rw-"); err == nil {
+		t.Error("expected a short mode to be invalid")
+	}
+}
@@ -0,0 +1,277 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+)
+
+// WritableFS is a filesystem that can be changed as well as read. [SessionFS]
+// implements it; code that depends on WritableFS rather than on a session can
+// be tested with a fake.
+type WritableFS interface {
+	fs.ReadDirFS
+	fs.ReadFileFS
+	fs.StatFS
+
+	// Create creates or truncates the named file and returns a writer to it.
+	Create(name string) (io.WriteCloser, error)
+	// OpenFile opens the named file for writing with the given os.O_* flags and
+	// permissions.
+	OpenFile(name string, flag int, perm fs.FileMode) (io.WriteCloser, error)
+	// MkdirAll creates the named directory and any missing parents.
+	MkdirAll(name string, perm fs.FileMode) error
+	// Remove removes the named file or empty directory.
+	Remove(name string) error
+	// RemoveAll removes the named file or directory and everything it contains.
+	// It returns nil if the name does not exist.
+	RemoveAll(name string) error
+	Rename(oldname, newname string) error
+	Chmod(name string, mode fs.FileMode) error
+}
+
+var _ WritableFS = (*SessionFS)(nil)
+
+// Create creates or truncates the named file. The returned writer streams what
+// is written to it to the file; the file is complete once Close returns nil.
+// The writer must be closed, as it holds a request open until then.
+func (f *SessionFS) Create(name string) (io.WriteCloser, error) {
+	return f.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0)
+}
+
+// OpenFile opens the named file for writing. The API always replaces a file's
+// contents, so the flags must include os.O_TRUNC, and os.O_RDWR and os.O_APPEND
+// are not supported; use Open to read. Without os.O_CREATE, the file must
+// exist, and with os.O_EXCL, it must not. Those two checks are made before
+// writing and are not atomic. A perm of 0 leaves the file's mode to the server,
+// which defaults to 0644.
+//
+// The returned writer must be closed, as it holds a request open until then. If
+// the filesystem's context (see [SessionFS.WithContext]) ends first, the request
+// is abandoned and further writes and Close return the context's error.
+func (f *SessionFS) OpenFile(name string, flag int, perm fs.FileMode) (io.WriteCloser, error) {
+	p, err := sessionFSPath("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if flag&(os.O_RDONLY|os.O_WRONLY|os.O_RDWR) != os.O_WRONLY || flag&os.O_APPEND != 0 || flag&os.O_TRUNC == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("%w: unsupported flags %#x", fs.ErrInvalid, flag)}
+	}
+	if flag&os.O_CREATE == 0 || flag&os.O_EXCL != 0 {
+		_, err := f.Stat(name)
+		switch {
+		case err == nil && flag&os.O_EXCL != 0:
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+		case err != nil && (flag&os.O_CREATE == 0 || !errors.Is(err, fs.ErrNotExist)):
+			return nil, replacePathErrorOp(err, "open")
+		}
+	}
+
+	params := BrowserFWriteFileParams{Path: p}
+	if perm != 0 {
+		params.Mode = String(fileModeOctal(perm))
+	}
+	pr, pw := io.Pipe()
+	w := &sessionFileWriter{name: name, pw: pw, done: make(chan error, 1)}
+	// Unblock the request, and any writes waiting on it, if the context ends
+	// before the writer is closed.
+	stop := context.AfterFunc(f.ctx, func() { pr.CloseWithError(f.ctx.Err()) })
+	go func() {
+		defer stop()
+		err := f.fs.WriteFile(f.ctx, pr, params, f.opts...)
+		// Fail the writes still waiting on the pipe if the request failed.
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w, nil
+}
+
+// sessionFileWriter streams a file to the API through a pipe.
+type sessionFileWriter struct {
+	name   string
+	pw     *io.PipeWriter
+	done   chan error
+	err    error // the request's result, once received from done
+	closed bool
+}
+
+func (w *sessionFileWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, &fs.PathError{Op: "write", Path: w.name, Err: fs.ErrClosed}
+	}
+	n, err := w.pw.Write(p)
+	if err != nil {
+		// The pipe is only closed once the request is ending; report why.
+		if reqErr := w.wait(); reqErr != nil {
+			err = reqErr
+		}
+		return n, sessionFSError("write", w.name, err)
+	}
+	return n, nil
+}
+
+// wait waits for the request to finish and returns its error.
+func (w *sessionFileWriter) wait() error {
+	if w.done != nil {
+		w.err = <-w.done
+		w.done = nil
+	}
+	return w.err
+}
+
+// Close ends the file and waits for the API to store it.
+func (w *sessionFileWriter) Close() error {
+	if w.closed {
+		return &fs.PathError{Op: "close", Path: w.name, Err: fs.ErrClosed}
+	}
+	w.closed = true
+	w.pw.Close()
+	if err := w.wait(); err != nil {
+		return sessionFSError("close", w.name, err)
+	}
+	return nil
+}
+
+// MkdirAll creates the named directory and any missing parents with the
+// permissions perm, or the server's default of 0755 if perm is 0. It returns
+// nil if the directory already exists.
+func (f *SessionFS) MkdirAll(name string, perm fs.FileMode) error {
+	p, err := sessionFSPath("mkdir", name)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat(name)
+	switch {
+	case err == nil && info.IsDir():
+		return nil
+	case err == nil:
+		return &fs.PathError{Op: "mkdir", Path: name, Err: errors.New("not a directory")}
+	case !errors.Is(err, fs.ErrNotExist):
+		return replacePathErrorOp(err, "mkdir")
+	}
+	if parent := path.Dir(name); parent != "." && parent != name {
+		if err := f.MkdirAll(parent, perm); err != nil {
+			return err
+		}
+	}
+	params := BrowserFNewDirectoryParams{Path: p}
+	if perm != 0 {
+		params.Mode = String(fileModeOctal(perm))
+	}
+	if err := f.fs.NewDirectory(f.ctx, params, f.opts...); err != nil {
+		// Another writer may have created it in the meantime.
+		var apierr *Error
+		if errors.As(err, &apierr) && apierr.StatusCode == http.StatusConflict {
+			return nil
+		}
+		return sessionFSError("mkdir", name, err)
+	}
+	return nil
+}
+
+// Remove removes the named file or empty directory.
+func (f *SessionFS) Remove(name string) error {
+	p, err := sessionFSPath("remove", name)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat(name)
+	if err != nil {
+		return replacePathErrorOp(err, "remove")
+	}
+	if !info.IsDir() {
+		if err := f.fs.DeleteFile(f.ctx, BrowserFDeleteFileParams{Path: p}, f.opts...); err != nil {
+			return sessionFSError("remove", name, err)
+		}
+		return nil
+	}
+	entries, err := f.ReadDir(name)
+	if err != nil {
+		return replacePathErrorOp(err, "remove")
+	}
+	if len(entries) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+	}
+	if err := f.fs.DeleteDirectory(f.ctx, BrowserFDeleteDirectoryParams{Path: p}, f.opts...); err != nil {
+		return sessionFSError("remove", name, err)
+	}
+	return nil
+}
+
+// RemoveAll removes the named file or directory and everything it contains. It
+// returns nil if the name does not exist.
+func (f *SessionFS) RemoveAll(name string) error {
+	p, err := sessionFSPath("removeall", name)
+	if err != nil {
+		return err
+	}
+	if p == "/" {
+		return &fs.PathError{Op: "removeall", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := f.Stat(name)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case err != nil:
+		return replacePathErrorOp(err, "removeall")
+	case info.IsDir():
+		err = f.fs.DeleteDirectory(f.ctx, BrowserFDeleteDirectoryParams{Path: p}, f.opts...)
+	default:
+		err = f.fs.DeleteFile(f.ctx, BrowserFDeleteFileParams{Path: p}, f.opts...)
+	}
+	if err != nil && !isNotFound(err) {
+		return sessionFSError("removeall", name, err)
+	}
+	return nil
+}
+
+// Rename moves oldname to newname.
+func (f *SessionFS) Rename(oldname, newname string) error {
+	src, err := sessionFSPath("rename", oldname)
+	if err != nil {
+		return err
+	}
+	dst, err := sessionFSPath("rename", newname)
+	if err != nil {
+		return err
+	}
+	if err := f.fs.Move(f.ctx, BrowserFMoveParams{SrcPath: src, DestPath: dst}, f.opts...); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fsAPIError(err)}
+	}
+	return nil
+}
+
+// Chmod changes the permissions of the named file, including the setuid,
+// setgid and sticky bits.
+func (f *SessionFS) Chmod(name string, mode fs.FileMode) error {
+	p, err := sessionFSPath("chmod", name)
+	if err != nil {
+		return err
+	}
+	params := BrowserFSetFilePermissionsParams{Path: p, Mode: fileModeOctal(mode)}
+	if err := f.fs.SetFilePermissions(f.ctx, params, f.opts...); err != nil {
+		return sessionFSError("chmod", name, err)
+	}
+	return nil
+}
+
+// fileModeOctal formats the permission and special bits of mode as chmod does,
+// such as "755" or "4755".
+func fileModeOctal(mode fs.FileMode) string {
+	bits := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		bits |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		bits |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		bits |= 0o1000
+	}
+	return fmt.Sprintf("%o", bits)
+}
@@ -0,0 +1,183 @@
+package kernel_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kernel/kernel-go-sdk"
+)
+
+func TestSessionFSCreate(t *testing.T) {
+	srv, session := startFSServer(t, map[string]string{"/tmp/old.txt": "old"})
+	fsys := session.FS()
+
+	w, err := fsys.Create("tmp/report.csv")
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(w, "row %d\n", i)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if got := srv.files["/tmp/report.csv"]; got != "row 0\nrow 1\nrow 2\n" {
+		t.Errorf("unexpected contents %q", got)
+	}
+	if _, err := w.Write([]byte("late")); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("expected a write after Close to fail, got %v", err)
+	}
+
+	w, err = fsys.OpenFile("tmp/run.sh", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o755)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	io.WriteString(w, "#!/bin/sh\n")
+	if err := w.Close(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if srv.modes["/tmp/run.sh"] != "755" {
+		t.Errorf("unexpected mode %q", srv.modes["/tmp/run.sh"])
+	}
+
+	w, err = fsys.Create("missing/dir/file.txt")
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	io.WriteString(w, "data")
+	if err := w.Close(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a write to a missing directory to fail, got %v", err)
+	}
+}
+
+func TestSessionFSCreateCancel(t *testing.T) {
+	_, session := startFSServer(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := session.FS().WithContext(ctx).Create("tmp/abandoned.txt")
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	cancel()
+	if _, err := io.WriteString(w, "data"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a write after cancellation to fail, got %v", err)
+	}
+	if err := w.Close(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected Close to report the cancellation, got %v", err)
+	}
+}
+
+func TestSessionFSOpenFileFlags(t *testing.T) {
+	_, session := startFSServer(t, map[string]string{"/tmp/old.txt": "old"})
+	fsys := session.FS()
+
+	for _, flag := range []int{os.O_RDONLY, os.O_RDWR | os.O_TRUNC, os.O_WRONLY | os.O_APPEND | os.O_TRUNC, os.O_WRONLY} {
+		if _, err := fsys.OpenFile("tmp/old.txt", flag, 0); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("expected flags %#x to be invalid, got %v", flag, err)
+		}
+	}
+	if _, err := fsys.OpenFile("tmp/new.txt", os.O_WRONLY|os.O_TRUNC, 0); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a missing file without O_CREATE to fail, got %v", err)
+	}
+	if _, err := fsys.OpenFile("tmp/old.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_TRUNC, 0); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected an existing file with O_EXCL to fail, got %v", err)
+	}
+	w, err := fsys.OpenFile("tmp/old.txt", os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if data, _ := fsys.ReadFile("tmp/old.txt"); len(data) != 0 {
+		t.Errorf("expected the file to be truncated, got %q", data)
+	}
+}
+
+func TestSessionFSDirectories(t *testing.T) {
+	srv, session := startFSServer(t, map[string]string{
+		"/tmp/keep.txt":       "keep",
+		"/tmp/cache/a/b.json": "{}",
+	})
+	fsys := session.FS()
+
+	if err := fsys.MkdirAll("tmp/out/2025/01", 0o700); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	for _, dir := range []string{"/tmp/out", "/tmp/out/2025", "/tmp/out/2025/01"} {
+		if !srv.dirs[dir] || srv.modes[dir] != "700" {
+			t.Errorf("expected %s to be created with mode 700, got %t %q", dir, srv.dirs[dir], srv.modes[dir])
+		}
+	}
+	if err := fsys.MkdirAll("tmp/out", 0); err != nil {
+		t.Errorf("expected an existing directory to be fine, got %v", err)
+	}
+	if err := fsys.MkdirAll("tmp/keep.txt/sub", 0); err == nil {
+		t.Error("expected a directory under a file to fail")
+	}
+
+	if err := fsys.Remove("tmp/cache"); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("expected removing a non-empty directory to fail, got %v", err)
+	}
+	if err := fsys.Remove("tmp/out/2025/01"); err != nil {
+		t.Errorf("err should be nil: %s", err.Error())
+	}
+	if err := fsys.Remove("tmp/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected removing a missing file to fail, got %v", err)
+	}
+	if err := fsys.RemoveAll("tmp/cache"); err != nil {
+		t.Errorf("err should be nil: %s", err.Error())
+	}
+	if err := fsys.RemoveAll("tmp/cache"); err != nil {
+		t.Errorf("expected removing a missing directory to be fine, got %v", err)
+	}
+	if err := fsys.RemoveAll("."); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("expected removing the root to be refused, got %v", err)
+	}
+
+	if err := fstest.TestFS(fsys, "tmp/keep.txt", "tmp/out/2025"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(fsys, "tmp/cache/a/b.json"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected the removed tree to be gone, got %v", err)
+	}
+}
+
+func TestSessionFSRenameChmod(t *testing.T) {
+	srv, session := startFSServer(t, map[string]string{"/tmp/a.txt": "a"})
+	var fsys kernel.WritableFS = session.FS()
+
+	if err := fsys.Rename("tmp/a.txt", "tmp/b.txt"); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if data, err := fsys.ReadFile("tmp/b.txt"); err != nil || string(data) != "a" {
+		t.Errorf("unexpected contents %q, %v", data, err)
+	}
+	var linkErr *os.LinkError
+	if err := fsys.Rename("tmp/a.txt", "tmp/c.txt"); !errors.As(err, &linkErr) || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a missing source to fail with a LinkError, got %v", err)
+	}
+
+	if err := fsys.Chmod("tmp/b.txt", fs.ModeSetuid|0o755); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if srv.modes["/tmp/b.txt"] != "4755" {
+		t.Errorf("unexpected mode %q", srv.modes["/tmp/b.txt"])
+	}
+	if err := fsys.Chmod("tmp/b.txt", 0o600); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	info, err := fsys.Stat("tmp/b.txt")
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if info.Mode() != 0o600 {
+		t.Errorf("unexpected mode %v", info.Mode())
+	}
+}
@@ -0,0 +1,94 @@
+package kernel
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+)
+
+// ShellParams configures [Session.Shell].
+type ShellParams struct {
+	// The command to run. Defaults to "/bin/bash".
+	Command string
+	Args    []string
+	// Environment variables in the form "key=value". TERM is copied from the
+	// local environment unless it is set here.
+	Env    []string
+	Dir    string
+	AsUser string
+	AsRoot bool
+	// The local terminal. They default to [os.Stdin] and [os.Stdout]. If Stdin
+	// is a terminal, it is put into raw mode while the shell runs, and its size
+	// is kept in sync with the remote terminal's.
+	Stdin  io.Reader
+	Stdout io.Writer
+}
+
+// Shell runs an interactive shell in the session, bridged to the local
+// terminal, and returns when the shell exits. The error is an [*ExitError] if
+// the shell exited with a non-zero status. The terminal is restored before
+// Shell returns.
+//
+// Once the shell exits, a read from Stdin that is already in progress keeps
+// going until it returns, and what it reads is dropped.
+func (s *Session) Shell(ctx context.Context, params ShellParams) error {
+	if params.Command == "" {
+		params.Command = "/bin/bash"
+	}
+	if params.Stdin == nil {
+		params.Stdin = os.Stdin
+	}
+	if params.Stdout == nil {
+		params.Stdout = os.Stdout
+	}
+	cmd := s.CommandContext(ctx, params.Command, params.Args...)
+	cmd.Env = params.Env
+	if term := os.Getenv("TERM"); term != "" && !hasEnvVar(params.Env, "TERM") {
+		cmd.Env = append(append([]string(nil), params.Env...), "TERM="+term)
+	}
+	cmd.Dir = params.Dir
+	cmd.AsUser = params.AsUser
+	cmd.AsRoot = params.AsRoot
+	cmd.Tty = true
+	cmd.Stdin = params.Stdin
+	cmd.Stdout = params.Stdout
+
+	fd, isTerm := terminalFd(params.Stdin)
+	if isTerm {
+		cmd.Cols, cmd.Rows, _ = terminalSize(fd)
+		restore, err := makeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if isTerm {
+		stop := watchTerminalSize(fd, func(cols, rows int) {
+			cmd.Process.Resize(cols, rows)
+		})
+		defer stop()
+	}
+	return cmd.Wait()
+}
+
+// terminalFd returns the file descriptor of r if r is a terminal.
+func terminalFd(r io.Reader) (uintptr, bool) {
+	f, ok := r.(interface{ Fd() uintptr })
+	if !ok {
+		return 0, false
+	}
+	return f.Fd(), isTerminal(f.Fd())
+}
+
+func hasEnvVar(env []string, name string) bool {
+	for _, kv := range env {
+		if k, _, _ := strings.Cut(kv, "="); k == name {
+			return true
+		}
+	}
+	return false
+}
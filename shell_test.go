@@ -0,0 +1,57 @@
+package kernel_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk"
+)
+
+func TestSessionShell(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color")
+	ps := &processServer{echo: true, stream: []string{"stdout:$ ", "exit:0"}, hang: true}
+	session := newProcessServer(t, ps)
+	var out bytes.Buffer
+	err := session.Shell(context.Background(), kernel.ShellParams{
+		Stdin:  strings.NewReader("ls\r"),
+		Stdout: &out,
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if out.String() != "ls\r$ " {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	spawn, _ := json.Marshal(ps.spawn)
+	if string(spawn) != `{"allocate_tty":true,"command":"/bin/bash","env":{"TERM":"xterm-256color"}}` {
+		t.Errorf("unexpected spawn request %s", spawn)
+	}
+}
+
+func TestCmdTty(t *testing.T) {
+	ps := &processServer{stream: []string{"exit:0"}}
+	session := newProcessServer(t, ps)
+	cmd := session.Command("top")
+	cmd.Tty, cmd.Cols, cmd.Rows = true, 120, 40
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if err := cmd.Process.Resize(100, 30); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.spawn["allocate_tty"] != true || ps.spawn["cols"] != 120.0 || ps.spawn["rows"] != 40.0 {
+		t.Errorf("unexpected spawn request %v", ps.spawn)
+	}
+	if fmt.Sprint(ps.resize) != "[100x30]" {
+		t.Errorf("expected the terminal to be resized, got %v", ps.resize)
+	}
+}
@@ -0,0 +1,166 @@
+package kernel
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"time"
+)
+
+// SuperviseParams configures [Session.Supervise].
+type SuperviseParams struct {
+	// The command to run and its arguments.
+	Command string
+	Args    []string
+	// Environment variables in the form "key=value".
+	Env    []string
+	Dir    string
+	AsUser string
+	AsRoot bool
+
+	// Name prefixed to the logged output. Defaults to the command's base name.
+	Name string
+	// Receives the process's output line by line, and its starts and exits.
+	// Defaults to [log.Default].
+	Logger *log.Logger
+	// Maximum number of restarts before giving up. Defaults to 5. A negative
+	// value means no limit.
+	MaxRestarts int
+	// Base delay before restarting, doubled on each restart and capped at 30
+	// seconds. Defaults to 1 second.
+	Backoff time.Duration
+	// A process that ran for at least this long before exiting is considered
+	// healthy, so its restart resets the backoff and the restart count. Defaults
+	// to 1 minute.
+	ResetAfter time.Duration
+	// How long to wait after TERM before sending KILL when ctx ends. Defaults to
+	// 10 seconds.
+	StopTimeout time.Duration
+}
+
+// Supervise runs a long-running process in the session, such as a proxy or a
+// recorder, and restarts it with backoff whenever it exits, until ctx ends.
+// Its output is logged line by line with the Name as prefix.
+//
+// When ctx ends, the process is sent TERM, then KILL if it has not exited
+// within StopTimeout, and Supervise returns ctx's error. If the process exits
+// more than MaxRestarts times in a row, Supervise returns an error describing
+// the last exit, which is an [*ExitError] if the process ran.
+func (s *Session) Supervise(ctx context.Context, params SuperviseParams) error {
+	if params.Name == "" {
+		params.Name = path.Base(params.Command)
+	}
+	if params.Logger == nil {
+		params.Logger = log.Default()
+	}
+	if params.MaxRestarts == 0 {
+		params.MaxRestarts = 5
+	}
+	if params.Backoff <= 0 {
+		params.Backoff = time.Second
+	}
+	if params.ResetAfter <= 0 {
+		params.ResetAfter = time.Minute
+	}
+	if params.StopTimeout <= 0 {
+		params.StopTimeout = 10 * time.Second
+	}
+
+	for restarts := 0; ; restarts++ {
+		started := time.Now()
+		err := s.superviseOnce(ctx, params)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			err = &ExitError{ProcessState: &ProcessState{}}
+		}
+		if time.Since(started) >= params.ResetAfter {
+			restarts = 0
+		}
+		if params.MaxRestarts >= 0 && restarts >= params.MaxRestarts {
+			return fmt.Errorf("kernel: %s gave up after %d restarts: %w", params.Name, restarts, err)
+		}
+		delay := retryBackoff(params.Backoff, restarts+1)
+		params.Logger.Printf("%s: %v; restarting in %s", params.Name, err, delay)
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// superviseOnce runs the process until it exits or ctx ends, and returns the
+// error of its Wait.
+func (s *Session) superviseOnce(ctx context.Context, params SuperviseParams) error {
+	// The command gets a context of its own so that ctx ending stops it with TERM
+	// rather than the KILL of a CommandContext.
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := s.CommandContext(runCtx, params.Command, params.Args...)
+	cmd.Env = params.Env
+	cmd.Dir = params.Dir
+	cmd.AsUser = params.AsUser
+	cmd.AsRoot = params.AsRoot
+	stdout := &logLineWriter{logger: params.Logger, prefix: params.Name + ": "}
+	stderr := &logLineWriter{logger: params.Logger, prefix: params.Name + " (stderr): "}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	params.Logger.Printf("%s: started with pid %d", params.Name, cmd.Process.Pid)
+
+	waited := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		stdout.flush()
+		stderr.flush()
+		waited <- err
+	}()
+	select {
+	case err := <-waited:
+		return err
+	case <-ctx.Done():
+	}
+
+	params.Logger.Printf("%s: stopping", params.Name)
+	cmd.Process.Signal(BrowserProcessKillParamsSignalTerm)
+	select {
+	case err := <-waited:
+		return err
+	case <-time.After(params.StopTimeout):
+	}
+	// Cancelling the command's context sends KILL and stops waiting for the
+	// exit event.
+	params.Logger.Printf("%s: did not stop within %s; killing", params.Name, params.StopTimeout)
+	cancel()
+	return <-waited
+}
+
+// logLineWriter logs what is written to it line by line.
+type logLineWriter struct {
+	logger *log.Logger
+	prefix string
+	buf    bytes.Buffer
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := w.buf.Next(i + 1)
+		w.logger.Print(w.prefix + string(bytes.TrimRight(line, "\r\n")))
+	}
+}
+
+// flush logs the last line if it has no line ending.
+func (w *logLineWriter) flush() {
+	if w.buf.Len() > 0 {
+		w.logger.Print(w.prefix + w.buf.String())
+		w.buf.Reset()
+	}
+}
@@ -0,0 +1,96 @@
+package kernel_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kernel/kernel-go-sdk"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSessionSuperviseRestarts(t *testing.T) {
+	ps := &processServer{stream: []string{"stdout:listening\nproxy up", "stderr:crashed\n", "exit:1"}}
+	session := newProcessServer(t, ps)
+	var logs syncBuffer
+	err := session.Supervise(context.Background(), kernel.SuperviseParams{
+		Command:     "/usr/bin/mitmdump",
+		Args:        []string{"-p", "8080"},
+		Logger:      log.New(&logs, "", 0),
+		MaxRestarts: 2,
+		Backoff:     time.Millisecond,
+	})
+	var ee *kernel.ExitError
+	if !errors.As(err, &ee) || ee.ExitCode() != 1 || !strings.Contains(err.Error(), "gave up after 2 restarts") {
+		t.Errorf("expected the supervisor to give up, got %v", err)
+	}
+	ps.mu.Lock()
+	spawns := ps.spawns
+	ps.mu.Unlock()
+	if spawns != 3 {
+		t.Errorf("expected 3 spawns, got %d", spawns)
+	}
+	for _, line := range []string{"mitmdump: started with pid 42", "mitmdump: listening", "mitmdump: proxy up", "mitmdump (stderr): crashed", "mitmdump: exit status 1; restarting in"} {
+		if !strings.Contains(logs.String(), line+"\n") && !strings.Contains(logs.String(), line+" ") {
+			t.Errorf("expected %q to be logged, got:\n%s", line, logs.String())
+		}
+	}
+}
+
+func TestSessionSuperviseStop(t *testing.T) {
+	ps := &processServer{stream: []string{"stdout:recording\n"}, hang: true}
+	session := newProcessServer(t, ps)
+	var logs syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Supervise(ctx, kernel.SuperviseParams{
+			Command:     "ffmpeg",
+			Logger:      log.New(&logs, "", 0),
+			StopTimeout: 50 * time.Millisecond,
+		})
+	}()
+	for !strings.Contains(logs.String(), "ffmpeg: recording") {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the context error, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("supervisor did not stop")
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if fmt.Sprint(ps.kills) != "[TERM KILL]" {
+		t.Errorf("expected TERM then KILL, got %v", ps.kills)
+	}
+	if ps.spawns != 1 {
+		t.Errorf("expected no restart after stopping, got %d spawns", ps.spawns)
+	}
+}
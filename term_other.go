@@ -0,0 +1,22 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package kernel
+
+import "errors"
+
+// Raw mode and window size changes are only supported on Unix, so elsewhere the
+// local terminal is left as it is.
+
+func isTerminal(fd uintptr) bool { return false }
+
+func makeRaw(fd uintptr) (restore func() error, err error) {
+	return nil, errors.New("kernel: raw terminal mode is not supported on this platform")
+}
+
+func terminalSize(fd uintptr) (cols, rows int, err error) {
+	return 0, 0, errors.New("kernel: terminal size is not supported on this platform")
+}
+
+func watchTerminalSize(fd uintptr, resize func(cols, rows int)) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,19 @@
+package kernel_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk"
+	"github.com/kernel/kernel-go-sdk/option"
+)
+
+// newTestClient serves handler until the test ends and returns a client for it.
+// The client keeps the default retry policy; tests that count requests pass
+// option.WithMaxRetries(0) themselves.
+func newTestClient(t *testing.T, handler http.Handler, opts ...option.RequestOption) kernel.Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return kernel.NewClient(append([]option.RequestOption{option.WithBaseURL(srv.URL), option.WithAPIKey("My API Key")}, opts...)...)
+}
@@ -0,0 +1,88 @@
+package kernel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// knownViewports are the viewports browser sessions and pools supported when this
+// SDK was released, largest first. The server may support others since, so the
+// table is only consulted by the helpers below and never by requests themselves.
+var knownViewports = [...]struct {
+	width, height, refreshRate int64
+}{
+	{2560, 1440, 10},
+	{1920, 1200, 25},
+	{1920, 1080, 25},
+	{1440, 900, 25},
+	{1200, 800, 60},
+	{1024, 768, 60},
+}
+
+// SupportedViewports returns the viewports known to be supported by browser
+// sessions and pools, largest first. The server may accept sizes added after this
+// SDK was released.
+func SupportedViewports() []BrowserViewportParam {
+	viewports := make([]BrowserViewportParam, len(knownViewports))
+	for i, v := range knownViewports {
+		viewports[i] = BrowserViewportParam{Width: v.width, Height: v.height, RefreshRate: Int(v.refreshRate)}
+	}
+	return viewports
+}
+
+// NewViewport returns the known viewport of the given size, with the refresh rate
+// the server would infer for it. It returns an error if the size is not known to
+// be supported.
+func NewViewport(width, height int64) (BrowserViewportParam, error) {
+	for _, v := range SupportedViewports() {
+		if v.Width == width && v.Height == height {
+			return v, nil
+		}
+	}
+	return BrowserViewportParam{}, unsupportedViewportError(width, height)
+}
+
+// ClosestViewport returns the known viewport whose size is closest to the
+// requested one.
+func ClosestViewport(width, height int64) BrowserViewportParam {
+	var best BrowserViewportParam
+	bestDist := int64(-1)
+	for _, v := range SupportedViewports() {
+		dw, dh := v.Width-width, v.Height-height
+		if dist := dw*dw + dh*dh; bestDist < 0 || dist < bestDist {
+			best, bestDist = v, dist
+		}
+	}
+	return best
+}
+
+// ValidateViewport reports whether v is a known viewport. An omitted viewport is
+// valid, as is one without a refresh rate whose size is known. Requests are not
+// validated automatically; call it before [BrowserService.New] or
+// [BrowserPoolService.New] to catch a bad viewport without a round trip:
+//
+//	if err := kernel.ValidateViewport(params.Viewport); err != nil {
+//		return err
+//	}
+func ValidateViewport(v BrowserViewportParam) error {
+	if v.Width == 0 && v.Height == 0 {
+		return nil
+	}
+	supported, err := NewViewport(v.Width, v.Height)
+	if err != nil {
+		return err
+	}
+	if v.RefreshRate.Valid() && v.RefreshRate.Value != supported.RefreshRate.Value {
+		return fmt.Errorf("kernel: unsupported refresh rate %d Hz for viewport %dx%d, which supports %d Hz", v.RefreshRate.Value, v.Width, v.Height, supported.RefreshRate.Value)
+	}
+	return nil
+}
+
+func unsupportedViewportError(width, height int64) error {
+	var sizes []string
+	for _, v := range SupportedViewports() {
+		sizes = append(sizes, fmt.Sprintf("%dx%d", v.Width, v.Height))
+	}
+	closest := ClosestViewport(width, height)
+	return fmt.Errorf("kernel: unsupported viewport %dx%d (closest is %dx%d); supported viewports are %s", width, height, closest.Width, closest.Height, strings.Join(sizes, ", "))
+}
@@ -0,0 +1,85 @@
+package kernel_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kernel/kernel-go-sdk"
+)
+
+func TestNewViewport(t *testing.T) {
+	v, err := kernel.NewViewport(1024, 768)
+	if err != nil || v.RefreshRate.Value != 60 {
+		t.Errorf("NewViewport(1024, 768) = %+v, %v", v, err)
+	}
+	if _, err := kernel.NewViewport(800, 600); err == nil || !strings.Contains(err.Error(), "closest is 1024x768") {
+		t.Errorf("expected an unsupported viewport error suggesting 1024x768, got %v", err)
+	}
+}
+
+func TestClosestViewport(t *testing.T) {
+	for _, tc := range []struct {
+		width, height int64
+		want          kernel.BrowserViewportParam
+	}{
+		{1920, 1080, kernel.BrowserViewportParam{Width: 1920, Height: 1080, RefreshRate: kernel.Int(25)}},
+		{1920, 1150, kernel.BrowserViewportParam{Width: 1920, Height: 1200, RefreshRate: kernel.Int(25)}},
+		{3840, 2160, kernel.BrowserViewportParam{Width: 2560, Height: 1440, RefreshRate: kernel.Int(10)}},
+		{1366, 768, kernel.BrowserViewportParam{Width: 1440, Height: 900, RefreshRate: kernel.Int(25)}},
+		{1280, 800, kernel.BrowserViewportParam{Width: 1200, Height: 800, RefreshRate: kernel.Int(60)}},
+		{800, 600, kernel.BrowserViewportParam{Width: 1024, Height: 768, RefreshRate: kernel.Int(60)}},
+	} {
+		got := kernel.ClosestViewport(tc.width, tc.height)
+		if got.Width != tc.want.Width || got.Height != tc.want.Height || got.RefreshRate != tc.want.RefreshRate {
+			t.Errorf("ClosestViewport(%d, %d) = %dx%d", tc.width, tc.height, got.Width, got.Height)
+		}
+	}
+}
+
+func TestValidateViewport(t *testing.T) {
+	for _, tc := range []struct {
+		viewport kernel.BrowserViewportParam
+		valid    bool
+	}{
+		{kernel.BrowserViewportParam{}, true},
+		{kernel.BrowserViewportParam{Width: 1920, Height: 1080}, true},
+		{kernel.BrowserViewportParam{Width: 2560, Height: 1440, RefreshRate: kernel.Int(10)}, true},
+		{kernel.BrowserViewportParam{Width: 1920, Height: 1080, RefreshRate: kernel.Int(60)}, false},
+		{kernel.BrowserViewportParam{Width: 1080, Height: 1920}, false},
+		{kernel.BrowserViewportParam{Width: 1280, Height: 800, RefreshRate: kernel.Int(60)}, false},
+	} {
+		if err := kernel.ValidateViewport(tc.viewport); (err == nil) != tc.valid {
+			t.Errorf("ValidateViewport(%dx%d) = %v", tc.viewport.Width, tc.viewport.Height, err)
+		}
+	}
+}
+
+func TestSupportedViewportsReturnsCopies(t *testing.T) {
+	viewports := kernel.SupportedViewports()
+	viewports[0].Width = 800
+	viewports[0].Height = 600
+	if err := kernel.ValidateViewport(kernel.BrowserViewportParam{Width: 800, Height: 600}); err == nil {
+		t.Error("expected changing the returned viewports not to change what is valid")
+	}
+}
+
+func TestViewportIsNotValidatedOnRequests(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"session_id":"sess_1","cdp_ws_url":"wss://cdp","created_at":"2025-01-01T00:00:00Z","headless":false,"stealth":false,"timeout_seconds":60}`)
+	}))
+	// A size added on the server after this SDK was released is still sent.
+	viewport := kernel.BrowserViewportParam{Width: 3840, Height: 2160}
+	if _, err := client.Browsers.New(context.Background(), kernel.BrowserNewParams{Viewport: viewport}); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected the request to be sent, got %d calls", calls.Load())
+	}
+}